@@ -0,0 +1,242 @@
+// Package archive decodes the file names of the ZIP and TAR archives to UTF-8.
+//
+// The archives created on Windows store the names in the OEM code page (like CP437 or CP866)
+// or in the ANSI code page (like windows-1252), without the UTF-8 flag,
+// and archive/zip and archive/tar return them as raw bytes in a string.
+// The encoding is detected once for the whole archive, using all the names as one sample.
+// The OEM code pages are not detected by utf8reader: the ZIP names without the UTF-8 flag
+// fall back to CP437, as specified by the ZIP format, if the detection is not conclusive
+// (see utf8reader.WithFallback). The other OEM code pages should be declared
+// with utf8reader.WithHint, like WithHint("cp866").
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kpym/utf8reader"
+)
+
+// zipUTF8Flag is the general purpose flag bit of the ZIP entries with UTF-8 names.
+const zipUTF8Flag = 0x800
+
+// errNewline is the error returned for the names containing a newline.
+var errNewline = errors.New("archive: newline in a file name")
+
+// DecodeNames converts the names to UTF-8, detecting the encoding on all the names at once
+// with the options. It returns the converted names and the detected encoding,
+// "" if all the names are ASCII.
+// Only the detection options (like WithHint and WithFallback) concern the names:
+// the transforms (like WithLineEndings or WithTransliteration) and the limits are not applied.
+func DecodeNames(names []string, options ...utf8reader.Option) ([]string, string, error) {
+	decoded := make([]string, len(names))
+	copy(decoded, names)
+	var idx []int // the indexes of the non-ASCII names
+	var sample []byte
+	for i, name := range names {
+		if isASCII(name) {
+			continue
+		}
+		if strings.IndexByte(name, '\n') >= 0 {
+			return nil, "", errNewline
+		}
+		if idx != nil {
+			sample = append(sample, '\n')
+		}
+		idx = append(idx, i)
+		sample = append(sample, name...)
+	}
+	if idx == nil {
+		return decoded, "", nil
+	}
+	// all the names are used for the detection, with the options
+	_, det, err := utf8reader.DecodeBytes(sample, options...)
+	if err != nil {
+		return nil, "", err
+	}
+	if det.Encoding == "" {
+		return decoded, "", nil
+	}
+	// and decoded without the transforms of the options
+	text, _, err := utf8reader.DecodeBytes(sample, utf8reader.WithHint(det.Encoding))
+	if err != nil {
+		return nil, "", err
+	}
+	lines := strings.Split(string(text), "\n")
+	if len(lines) != len(idx) {
+		return nil, "", errors.New("archive: the names can not be decoded with " + det.Encoding)
+	}
+	for j, i := range idx {
+		decoded[i] = lines[j]
+	}
+	return decoded, det.Encoding, nil
+}
+
+// isASCII returns true if s is ASCII.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeZipNames converts, in place, the names of the files of zr that have not the UTF-8 flag
+// (see DecodeNames), and returns the detected encoding.
+// If the detection is not conclusive the names are decoded as CP437,
+// unless another fallback is set by the options (see utf8reader.WithFallback).
+// It should be called before zr.Open, that indexes the names.
+func DecodeZipNames(zr *zip.Reader, options ...utf8reader.Option) (string, error) {
+	var files []*zip.File
+	var names []string
+	for _, f := range zr.File {
+		if f.Flags&zipUTF8Flag == 0 {
+			files = append(files, f)
+			names = append(names, f.Name)
+		}
+	}
+	// the default encoding of the ZIP names, the options can change it
+	options = append([]utf8reader.Option{utf8reader.WithFallback("cp437")}, options...)
+	decoded, encoding, err := DecodeNames(names, options...)
+	if err != nil {
+		return "", err
+	}
+	for i, f := range files {
+		f.Name = decoded[i]
+		f.NonUTF8 = false
+	}
+	return encoding, nil
+}
+
+// OpenZipFile opens the content of f converted to UTF-8 with the options (see utf8reader.NewReader).
+func OpenZipFile(f *zip.File, options ...utf8reader.Option) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	ur, err := utf8reader.NewReader(rc, options...)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{ur, rc}, nil
+}
+
+// WalkZip converts the names of zr (see DecodeZipNames) and calls fn for each file,
+// with its content converted to UTF-8 if convert is true (see OpenZipFile), or raw otherwise.
+// The content of the directories is never converted.
+// WalkZip stops at the first error returned by fn, and returns it.
+func WalkZip(zr *zip.Reader, convert bool, fn func(f *zip.File, content io.Reader) error, options ...utf8reader.Option) error {
+	if _, err := DecodeZipNames(zr, options...); err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		var rc io.ReadCloser
+		var err error
+		if convert && !f.FileInfo().IsDir() {
+			rc, err = OpenZipFile(f, options...)
+		} else {
+			rc, err = f.Open()
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(f, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeTarHeaders converts, in place, the names and the link names of the headers
+// (see DecodeNames), and returns the detected encoding.
+// The names set by PAX records, that are UTF-8, are not converted.
+func DecodeTarHeaders(hdrs []*tar.Header, options ...utf8reader.Option) (string, error) {
+	type field struct {
+		hdr  *tar.Header
+		link bool
+	}
+	var fields []field
+	var names []string
+	for _, h := range hdrs {
+		if _, ok := h.PAXRecords["path"]; !ok {
+			fields = append(fields, field{h, false})
+			names = append(names, h.Name)
+		}
+		if _, ok := h.PAXRecords["linkpath"]; !ok && h.Linkname != "" {
+			fields = append(fields, field{h, true})
+			names = append(names, h.Linkname)
+		}
+	}
+	decoded, encoding, err := DecodeNames(names, options...)
+	if err != nil {
+		return "", err
+	}
+	for i, f := range fields {
+		if f.link {
+			f.hdr.Linkname = decoded[i]
+		} else {
+			f.hdr.Name = decoded[i]
+		}
+	}
+	return encoding, nil
+}
+
+// WalkTar reads the TAR archive r twice: first to detect the encoding of all the names
+// (see DecodeTarHeaders), and then to call fn for each entry, with the converted header
+// and the content of the entry, converted to UTF-8 with the options if convert is true
+// (see utf8reader.NewReader), or raw otherwise.
+// Only the content of the regular files is converted.
+// WalkTar stops at the first error returned by fn, and returns it.
+func WalkTar(r io.ReadSeeker, convert bool, fn func(hdr *tar.Header, content io.Reader) error, options ...utf8reader.Option) error {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	var hdrs []*tar.Header
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		hdrs = append(hdrs, h)
+	}
+	if _, err := DecodeTarHeaders(hdrs, options...); err != nil {
+		return err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	tr = tar.NewReader(r)
+	for _, h := range hdrs {
+		if _, err := tr.Next(); err != nil {
+			return err
+		}
+		var content io.Reader = tr
+		if convert && h.Typeflag == tar.TypeReg {
+			ur, err := utf8reader.NewReader(tr, options...)
+			if err != nil {
+				return err
+			}
+			content = ur
+		}
+		if err := fn(h, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
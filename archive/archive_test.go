@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kpym/utf8reader"
+)
+
+func TestDecodeNames(t *testing.T) {
+	names := []string{"readme.txt", "\xc4\xee\xea\xf3\xec\xe5\xed\xf2\xfb/\xee\xf2\xf7\xe5\xf2.doc", "\xcf\xf0\xe8\xec\xe5\xf0\xfb \xf1\xf7\xe5\xf2\xee\xe2.xls"}
+	got, encoding, err := DecodeNames(names)
+	want := []string{"readme.txt", "Документы/отчет.doc", "Примеры счетов.xls"}
+	if err != nil || strings.Join(got, "|") != strings.Join(want, "|") || encoding != "windows-1251" {
+		t.Errorf("DecodeNames() = %q, %s, %v, want %q, windows-1251, nil", got, encoding, err, want)
+	}
+
+	// OEM code page, declared
+	got, encoding, err = DecodeNames([]string{"R\x82sum\x82.txt", "caf\x82.txt"}, utf8reader.WithHint("cp437"))
+	if err != nil || strings.Join(got, "|") != "Résumé.txt|café.txt" || encoding != "cp437" {
+		t.Errorf("DecodeNames(cp437) = %q, %s, %v, want [Résumé.txt café.txt], cp437, nil", got, encoding, err)
+	}
+
+	// the transforms of the options do not concern the names
+	got, encoding, err = DecodeNames(names, utf8reader.WithLineEndings(utf8reader.CRLF), utf8reader.WithTransliteration("ru"))
+	if err != nil || strings.Join(got, "|") != strings.Join(want, "|") || encoding != "windows-1251" {
+		t.Errorf("DecodeNames(CRLF, ru) = %q, %s, %v, want %q, windows-1251, nil", got, encoding, err, want)
+	}
+
+	// ASCII and UTF-8
+	if _, encoding, _ := DecodeNames([]string{"a.txt"}); encoding != "" {
+		t.Errorf("DecodeNames(ASCII) encoding = %q, want \"\"", encoding)
+	}
+	if got, encoding, _ := DecodeNames([]string{"café.txt"}); got[0] != "café.txt" || encoding != "UTF-8" {
+		t.Errorf("DecodeNames(UTF-8) = %q, %s, want [café.txt], UTF-8", got, encoding)
+	}
+	if _, _, err := DecodeNames([]string{"caf\xe9\n.txt"}); err == nil {
+		t.Errorf("DecodeNames(newline) error = nil, want non nil")
+	}
+}
+
+func TestZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name    string
+		utf8    bool
+		content string
+	}{
+		{"caf\x82 cr\x8ame.txt", false, "caf\xe9 cr\xe8me br\xfbl\xe9e"},
+		{"cr\x8ame br\x96l\x82e.txt", false, "ok"},
+		{"ünïcode.txt", true, "ok"},
+	} {
+		h := &zip.FileHeader{Name: f.name, NonUTF8: !f.utf8}
+		w, _ := zw.CreateHeader(h)
+		io.WriteString(w, f.content)
+	}
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the names fall back to CP437
+	encoding, err := DecodeZipNames(zr)
+	if err != nil || encoding != "cp437" {
+		t.Errorf("DecodeZipNames() = %s, %v, want cp437, nil", encoding, err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, "|"); got != "café crème.txt|crème brûlée.txt|ünïcode.txt" {
+		t.Errorf("names = %q", got)
+	}
+	rc, err := OpenZipFile(zr.File[0])
+	if err != nil {
+		t.Fatalf("OpenZipFile() error = %v, want nil", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "café crème brûlée" {
+		t.Errorf("OpenZipFile() content = %q, want \"café crème brûlée\"", b)
+	}
+	if _, err := zr.Open("crème brûlée.txt"); err != nil {
+		t.Errorf("zr.Open(decoded name) error = %v, want nil", err)
+	}
+
+	for _, convert := range []bool{false, true} {
+		zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		var got []string
+		err := WalkZip(zr, convert, func(f *zip.File, r io.Reader) error {
+			b, _ := io.ReadAll(r)
+			got = append(got, f.Name+":"+string(b))
+			return nil
+		})
+		want := "café crème.txt:caf\xe9 cr\xe8me br\xfbl\xe9e|crème brûlée.txt:ok|ünïcode.txt:ok"
+		if convert {
+			want = "café crème.txt:café crème brûlée|crème brûlée.txt:ok|ünïcode.txt:ok"
+		}
+		if err != nil || strings.Join(got, "|") != want {
+			t.Errorf("WalkZip(%v) = %q, %v, want %q, nil", convert, got, err, want)
+		}
+	}
+}
+
+func TestWalkTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range []*tar.Header{
+		{Name: "dir/\xcf\xfa\xf0\xe2\xe8 \xf0\xe5\xe4.txt", Size: 2, Format: tar.FormatGNU},
+		{Name: "dir/\xc2\xf2\xee\xf0\xe8 \xf0\xe5\xe4.txt", Size: 17, Format: tar.FormatGNU},
+		{Name: "link", Linkname: "dir/\xc2\xf2\xee\xf0\xe8 \xf0\xe5\xe4.txt", Typeflag: tar.TypeSymlink, Format: tar.FormatGNU},
+	} {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size == 17 {
+			io.WriteString(tw, "caf\xe9 cr\xe8me br\xfbl\xe9e")
+		} else {
+			io.WriteString(tw, strings.Repeat("x", int(h.Size)))
+		}
+	}
+	tw.Close()
+
+	for _, convert := range []bool{false, true} {
+		var got []string
+		err := WalkTar(bytes.NewReader(buf.Bytes()), convert, func(h *tar.Header, r io.Reader) error {
+			b, _ := io.ReadAll(r)
+			got = append(got, h.Name+":"+h.Linkname+":"+string(b))
+			return nil
+		})
+		want := "dir/Първи ред.txt::xx|dir/Втори ред.txt::caf\xe9 cr\xe8me br\xfbl\xe9e|link:dir/Втори ред.txt:"
+		if convert {
+			want = "dir/Първи ред.txt::xx|dir/Втори ред.txt::café crème brûlée|link:dir/Втори ред.txt:"
+		}
+		if err != nil || strings.Join(got, "|") != want {
+			t.Errorf("WalkTar(%v) = %q, %v, want %q, nil", convert, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package utf8reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// benchEncodings are the encodings used by the benchmarks, with the text to encode.
+var benchEncodings = []struct {
+	name string
+	enc  encoding.Encoding
+	text string
+}{
+	{"ASCII", nil, "This is a plain ASCII line of text, the most common case.\n"},
+	{"UTF-8", nil, "Това е ред на български, c'est bête en français.\n"},
+	{"windows-1251", charmap.Windows1251, "Това е ред на български, глупаво е но работи.\n"},
+	{"UTF-16LE", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "Това е ред на български, c'est bête en français.\n"},
+	{"Shift_JIS", japanese.ShiftJIS, "日本語のテキストです。これはテストです。\n"},
+}
+
+// benchSizes are the sizes of the input used by the benchmarks.
+var benchSizes = []int{1 << 10, 64 << 10, 1 << 20}
+
+// benchInput returns the text encoded with enc, repeated up to size bytes
+// and cut at a character boundary.
+func benchInput(tb testing.TB, enc encoding.Encoding, text string, size int) []byte {
+	// the encoded characters of the text
+	var chars [][]byte
+	for _, r := range text {
+		c := []byte(string(r))
+		if enc != nil {
+			var err error
+			if c, err = enc.NewEncoder().Bytes(c); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		chars = append(chars, c)
+	}
+	var in []byte
+	for i := 0; len(in)+len(chars[i]) <= size; i = (i + 1) % len(chars) {
+		in = append(in, chars[i]...)
+	}
+	return in
+}
+
+func TestBenchInput(t *testing.T) {
+	for _, e := range benchEncodings {
+		want := e.name
+		if want == "ASCII" {
+			want = "UTF-8"
+		}
+		for _, size := range append(benchSizes, 4096) {
+			in := benchInput(t, e.enc, e.text, size)
+			if len(in) > size || len(in) < size-4 {
+				t.Errorf("%s: len(benchInput(%d)) = %d", e.name, size, len(in))
+			}
+			if got := detectCharset(in); got != want {
+				t.Errorf("%s: detectCharset(benchInput(%d)) = %s, want %s", e.name, size, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	for _, e := range benchEncodings {
+		for _, size := range benchSizes {
+			in := benchInput(b, e.enc, e.text, size)
+			b.Run(fmt.Sprintf("%s/%d", e.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					New(bytes.NewReader(in))
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	for _, e := range benchEncodings {
+		for _, size := range benchSizes {
+			in := benchInput(b, e.enc, e.text, size)
+			b.Run(fmt.Sprintf("%s/%d", e.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(in)))
+				for range b.N {
+					if _, err := io.Copy(io.Discard, New(bytes.NewReader(in))); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDetectCharset(b *testing.B) {
+	for _, e := range benchEncodings {
+		in := benchInput(b, e.enc, e.text, 4096)
+		b.Run(e.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(in)))
+			for range b.N {
+				detectCharset(in)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package utf8reader
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// splitFunc returns the index in buf of a safe boundary, where the encoded input
+// can be split in two parts that can be decoded independently, or -1 if none was found.
+// The buf should start at a position aligned on 4 bytes from the start of the text.
+type splitFunc func(buf []byte) int
+
+// newSplitFunc returns the splitFunc for the encoding.
+// The boundary is preferably just after a new line, so that the normalization
+// and the other transforms do not depend on the split.
+// For the fixed width encodings and UTF-8 any character boundary
+// is used as fallback. The stateful encodings can not be split.
+func newSplitFunc(enc string) splitFunc {
+	if isStateful(enc) {
+		return func([]byte) int { return -1 }
+	}
+	nl, unit := []byte{'\n'}, unitSize(enc)
+	// the aliases, like "utf-16", have the new lines of their canonical encoding
+	enc = canonicalName(enc)
+	switch enc {
+	case "UTF-16LE":
+		nl = []byte{'\n', 0}
+	case "UTF-16BE":
+		nl = []byte{0, '\n'}
+	case "UTF-32LE":
+		nl = []byte{'\n', 0, 0, 0}
+	case "UTF-32BE":
+		nl = []byte{0, 0, 0, '\n'}
+	}
+	utf16be := enc == "UTF-16BE"
+	isUTF8 := enc == "" || enc == "UTF-8"
+	return func(buf []byte) int {
+		// after a new line
+		for i := 0; i+len(nl) <= len(buf); {
+			k := bytes.Index(buf[i:], nl)
+			if k < 0 {
+				break
+			}
+			if k = i + k; unit < 2 || k%unit == 0 {
+				return k + len(nl)
+			}
+			i = k + 1
+		}
+		// at a character boundary
+		switch {
+		case unit == 2:
+			// not inside a surrogate pair
+			for i := 2; i+1 < len(buf); i += 2 {
+				hi := buf[i-1]
+				if utf16be {
+					hi = buf[i-2]
+				}
+				if hi < 0xD8 || hi > 0xDB {
+					return i
+				}
+			}
+		case unit > 0:
+			return 0
+		case isUTF8:
+			for i := 0; i < len(buf) && i < utf8.UTFMax; i++ {
+				if utf8.RuneStart(buf[i]) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+}
@@ -0,0 +1,149 @@
+// Command utf8reader converts text files in any encoding to UTF-8.
+//
+// Usage:
+//
+//	utf8reader convert [flags] [file ...]
+//	utf8reader detect [flags] [file ...]
+//
+// The convert command detects the encoding of each file (or of the standard input
+// if no file is given) and writes the UTF-8 converted text to the standard output.
+// With the -parallel flag, the regular files are split in chunks decoded concurrently.
+// Run "utf8reader convert -h" for the list of flags.
+//
+// The detect command prints the detected encoding of each file (or of the standard input).
+// With the -explain flag, it also prints each step of the detection,
+// to understand why an encoding was chosen.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kpym/utf8reader"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "convert":
+		err = convert(os.Args[2:])
+	case "detect":
+		err = detect(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "utf8reader:", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the usage and exits.
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: utf8reader convert [flags] [file ...]")
+	fmt.Fprintln(os.Stderr, "       utf8reader detect [flags] [file ...]")
+	os.Exit(2)
+}
+
+// convert runs the convert command.
+func convert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	var o utf8reader.Options
+	o.RegisterFlags(fs)
+	parallel := fs.Int("parallel", 1, "number of workers decoding the regular files concurrently (0 for the number of CPUs), not with -lazy and the limits")
+	fs.Parse(args)
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if *parallel != 1 && (o.LazyDetection || o.MaxInputBytes != 0 || o.MaxOutputBytes != 0 || o.MaxExpansionRatio != 0) {
+		return errors.New("-parallel can not be used with -lazy, -max-input, -max-output and -max-expansion")
+	}
+	if fs.NArg() == 0 {
+		return convertReader(os.Stdout, os.Stdin, o)
+	}
+	for _, name := range fs.Args() {
+		if err := convertFile(os.Stdout, name, o, *parallel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertFile writes the UTF-8 converted content of the file name to w.
+func convertFile(w io.Writer, name string, o utf8reader.Options, parallel int) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() && parallel != 1 {
+		_, err = utf8reader.DecodeParallel(w, f, fi.Size(), parallel, utf8reader.WithOptions(o))
+		return err
+	}
+	return convertReader(w, f, o)
+}
+
+// convertReader writes the UTF-8 converted content of r to w.
+func convertReader(w io.Writer, r io.Reader, o utf8reader.Options) error {
+	reader, err := utf8reader.NewReader(r, utf8reader.WithOptions(o))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// detect runs the detect command.
+func detect(args []string) error {
+	fs := flag.NewFlagSet("detect", flag.ExitOnError)
+	var o utf8reader.Options
+	o.RegisterFlags(fs)
+	explain := fs.Bool("explain", false, "print the steps of the detection")
+	fs.Parse(args)
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return detectReader(os.Stdout, "<stdin>", os.Stdin, o, *explain)
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = detectReader(os.Stdout, name, f, o, *explain)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detectReader writes the encoding of r to w, with the detection steps if explain is set.
+func detectReader(w io.Writer, name string, r io.Reader, o utf8reader.Options, explain bool) error {
+	var events []utf8reader.Event
+	reader, err := utf8reader.NewReader(r, utf8reader.WithOptions(o), utf8reader.WithTrace(func(e utf8reader.Event) {
+		events = append(events, e)
+	}))
+	if err != nil {
+		return err
+	}
+	encoding := reader.Encoding()
+	if encoding == "" {
+		encoding = "unknown"
+	}
+	fmt.Fprintf(w, "%s: %s\n", name, encoding)
+	if explain {
+		for _, e := range events {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package utf8reader
+
+import (
+	"context"
+	"io"
+)
+
+// ctxReader is an io.Reader that honors the cancellation of a context.
+// Each Read of the underlying reader is done in a goroutine, in an internal buffer,
+// so a Read blocked on the underlying reader returns ctx.Err() as soon as ctx is done.
+// The blocked Read is then abandoned, its result is never used,
+// and all the subsequent Reads return ctx.Err().
+type ctxReader struct {
+	ctx  context.Context
+	r    io.Reader
+	buf  []byte          // the buffer of the pending read
+	done chan readResult // the result of the pending read, nil if none
+}
+
+// readResult is the result of a Read call.
+type readResult struct {
+	n   int
+	err error
+}
+
+// Read reads from the underlying reader until it returns or ctx is done.
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.done == nil {
+		if cap(r.buf) < len(p) {
+			r.buf = make([]byte, len(p))
+		}
+		buf, done := r.buf[:len(p)], make(chan readResult, 1)
+		go func() {
+			n, err := r.r.Read(buf)
+			done <- readResult{n, err}
+		}()
+		r.done = done
+	}
+	select {
+	case res := <-r.done:
+		r.done = nil
+		return copy(p, r.buf[:res.n]), res.err
+	case <-r.ctx.Done():
+		// the pending read is abandoned, with its buffer
+		r.buf = nil
+		return 0, r.ctx.Err()
+	}
+}
+
+// NewContext is like NewReader but the peek of the first bytes and all the reads
+// of the input honor the cancellation and the deadline of ctx:
+// if ctx is done, they return ctx.Err() without waiting for a blocked input.
+// The input is read in a separate goroutine, that stays blocked
+// until the input returns, so a blocked input should eventually be closed.
+// The input is hidden behind a context-aware reader, so the sampling of seekable inputs
+// (see WithSampling) does not apply.
+func NewContext(ctx context.Context, r io.Reader, options ...Option) (*Reader, error) {
+	if r == nil {
+		return NewReader(nil, options...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return NewReader(&ctxReader{ctx: ctx, r: r}, options...)
+}
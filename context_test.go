@@ -0,0 +1,97 @@
+package utf8reader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingReader is a reader that blocks until it is closed.
+type blockingReader struct {
+	closed chan struct{}
+}
+
+func newBlockingReader() *blockingReader {
+	return &blockingReader{closed: make(chan struct{})}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	<-r.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (r *blockingReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestNewContext(t *testing.T) {
+	const text = "Това е текст на български език.\n"
+	in, _ := lookup("windows-1251").NewEncoder().String(strings.Repeat(text, 300))
+
+	// not canceled
+	r, err := NewContext(context.Background(), strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("NewContext(Background, ...) error = %v, want nil", err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != strings.Repeat(text, 300) || r.Encoding() != "windows-1251" {
+		t.Errorf("NewContext(Background, ...): %v, %s, want the text, nil, windows-1251", err, r.Encoding())
+	}
+
+	// already canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if r, err := NewContext(ctx, strings.NewReader(in)); r != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("NewContext(canceled, ...) = %v, %v, want nil, context.Canceled", r, err)
+	}
+
+	// nil reader
+	if _, err := NewContext(context.Background(), nil); err == nil {
+		t.Errorf("NewContext(Background, nil) error = nil, want an error")
+	}
+}
+
+func TestNewContext_blockedPeek(t *testing.T) {
+	br := newBlockingReader()
+	defer br.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	r, err := NewContext(ctx, io.MultiReader(strings.NewReader("some text"), br))
+	if r != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("NewContext(...) = %v, %v, want nil, context.Canceled", r, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("NewContext(...) returned after %v, want promptly", d)
+	}
+}
+
+func TestNewContext_blockedRead(t *testing.T) {
+	br := newBlockingReader()
+	defer br.Close()
+	head := strings.Repeat("ASCII text that fills the peek buffer.\n", 200)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	r, err := NewContext(ctx, io.MultiReader(strings.NewReader(head), br), WithPeekSize(MinPeekSize))
+	if err != nil {
+		t.Fatalf("NewContext(...) error = %v, want nil", err)
+	}
+	start := time.Now()
+	b, err := io.ReadAll(r)
+	if string(b) != head || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("io.ReadAll(r) = %d bytes, %v, want %d bytes, context.DeadlineExceeded", len(b), err, len(head))
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("io.ReadAll(r) returned after %v, want promptly", d)
+	}
+	// the reader stays done
+	if n, err := r.Read(make([]byte, 10)); n != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("r.Read(...) = %d, %v, want 0, context.DeadlineExceeded", n, err)
+	}
+}
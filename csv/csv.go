@@ -0,0 +1,131 @@
+// Package csv reads CSV and TSV files in any encoding, like the Excel exports.
+//
+// Excel exports the CSV files in the ANSI code page of the system (like windows-1252),
+// and the "Unicode Text" files in UTF-16LE with tabs. NewReader converts them to UTF-8
+// (see utf8reader.New), skips the "sep=;" directive line written by Excel,
+// sniffs the delimiter and returns a configured *csv.Reader of the standard library.
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	stdcsv "encoding/csv"
+	"io"
+	"unicode/utf8"
+
+	"github.com/kpym/utf8reader"
+)
+
+// Delimiters are the candidates of SniffDelimiter, by order of preference.
+var Delimiters = []rune{',', ';', '\t', '|'}
+
+// sniffSize is the number of bytes used to sniff the delimiter.
+const sniffSize = 16 << 10
+
+// maxRecords is the number of records used to sniff the delimiter.
+const maxRecords = 20
+
+// NewReader returns a *csv.Reader reading r converted to UTF-8 with the options.
+// If the first line is an Excel directive like "sep=;", it is skipped and sets the delimiter,
+// otherwise the delimiter is sniffed from the first records (see SniffDelimiter).
+// It returns an error if some option is invalid or if the first bytes can not be read.
+func NewReader(r io.Reader, options ...utf8reader.Option) (*stdcsv.Reader, error) {
+	ur, err := utf8reader.NewReader(r, options...)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(ur, sniffSize)
+	sample, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	comma, n := sepDirective(sample)
+	if n > 0 {
+		br.Discard(n)
+	} else {
+		comma = SniffDelimiter(sample)
+	}
+	cr := stdcsv.NewReader(br)
+	cr.Comma = comma
+	return cr, nil
+}
+
+// sepDirective returns the delimiter set by the Excel directive "sep=X"
+// on the first line of sample, and the length of the line, or 0 if there is no directive.
+func sepDirective(sample []byte) (rune, int) {
+	line, _, found := bytes.Cut(sample, []byte{'\n'})
+	n := len(line)
+	if found {
+		n++
+	}
+	line = bytes.TrimRight(line, "\r")
+	if len(line) < 5 || !bytes.EqualFold(line[:4], []byte("sep=")) {
+		return 0, 0
+	}
+	comma, size := utf8.DecodeRune(line[4:])
+	if 4+size != len(line) || !validDelimiter(comma) {
+		return 0, 0
+	}
+	return comma, n
+}
+
+// validDelimiter returns true if r can be used as delimiter by a csv.Reader.
+func validDelimiter(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && r != utf8.RuneError
+}
+
+// SniffDelimiter returns the delimiter of the CSV sample, one of the Delimiters,
+// or ',' if none fits. It counts the delimiters, outside the quoted fields,
+// in the first records: the delimiter found the same number of times
+// in the most records wins, the most frequent one in case of tie.
+// The last record is ignored if the sample is truncated.
+func SniffDelimiter(sample []byte) rune {
+	records := countDelimiters(sample)
+	best, bestScore, bestCount := ',', 0, 0
+	for i, d := range Delimiters {
+		if len(records) == 0 || records[0][i] == 0 {
+			continue
+		}
+		first, score := records[0][i], 0
+		for _, counts := range records {
+			if counts[i] == first {
+				score++
+			}
+		}
+		if score > bestScore || score == bestScore && first > bestCount {
+			best, bestScore, bestCount = d, score, first
+		}
+	}
+	return best
+}
+
+// countDelimiters returns the number of each delimiter in the first complete records of sample.
+func countDelimiters(sample []byte) [][]int {
+	var records [][]int
+	counts := make([]int, len(Delimiters))
+	quoted := false
+	for _, c := range string(sample) {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '\n':
+			records = append(records, counts)
+			if len(records) == maxRecords {
+				return records
+			}
+			counts = make([]int, len(Delimiters))
+		default:
+			for i, d := range Delimiters {
+				if c == d {
+					counts[i]++
+				}
+			}
+		}
+	}
+	if len(sample) < sniffSize && !quoted && len(sample) > 0 && sample[len(sample)-1] != '\n' {
+		// the last record is complete, without end of line
+		records = append(records, counts)
+	}
+	return records
+}
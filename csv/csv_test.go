@@ -0,0 +1,66 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpym/utf8reader"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		sample string
+		want   rune
+	}{
+		{"a,b,c\n1,2,3\n", ','},
+		{"a;b;c\n1,5;2,5;3\n", ';'},
+		{"a\tb\n1\t2\n", '\t'},
+		{"a|b\n1|2", '|'},
+		{"\"a;b\",c\n\"1;2\",3\n", ','},
+		{"name\nvalue\n", ','},
+		{"", ','},
+	}
+	for _, tt := range tests {
+		if got := SniffDelimiter([]byte(tt.sample)); got != tt.want {
+			t.Errorf("SniffDelimiter(%q) = %q, want %q", tt.sample, got, tt.want)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String("nom\tprix\r\ncafé\t2,5\r\ncrème brûlée\t7\r\n")
+	ansi, _ := charmap.Windows1252.NewEncoder().String("sep=;\r\nnom;prix\r\ncafé;2,5\r\ncrème brûlée;7\r\n")
+	tests := []struct {
+		name  string
+		in    string
+		comma rune
+	}{
+		{"unicode text", utf16, '\t'},
+		{"ansi with sep", ansi, ';'},
+		{"utf-8 comma", "nom,prix\ncafé,\"2,5\"\ncrème brûlée,7\n", ','},
+	}
+	want := "nom|prix/café|2,5/crème brûlée|7"
+	for _, tt := range tests {
+		r, err := NewReader(strings.NewReader(tt.in))
+		if err != nil {
+			t.Fatalf("%s: NewReader() error = %v, want nil", tt.name, err)
+		}
+		if r.Comma != tt.comma {
+			t.Errorf("%s: r.Comma = %q, want %q", tt.name, r.Comma, tt.comma)
+		}
+		records, err := r.ReadAll()
+		var got []string
+		for _, rec := range records {
+			got = append(got, strings.Join(rec, "|"))
+		}
+		if err != nil || strings.Join(got, "/") != want {
+			t.Errorf("%s: r.ReadAll() = %q, %v, want %q, nil", tt.name, got, err, want)
+		}
+	}
+
+	if _, err := NewReader(strings.NewReader("a,b"), utf8reader.WithPeekSize(1)); err == nil {
+		t.Errorf("NewReader(..., WithPeekSize(1)) error = nil, want non nil")
+	}
+}
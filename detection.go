@@ -0,0 +1,84 @@
+package utf8reader
+
+import (
+	"bytes"
+	"log/slog"
+
+	"golang.org/x/text/transform"
+)
+
+// Detection is the result of the encoding detection.
+type Detection struct {
+	// Encoding is the detected encoding, "" if unknown or if the input is empty.
+	// The ASCII input is detected as "UTF-8" with a zero confidence.
+	Encoding string
+	// Confidence is the confidence of the detection, between 0 and 100.
+	// It is 100 for a BOM, a used hint (see WithHint) or valid non-ASCII UTF-8.
+	// It is 0 for the fallback charset (see WithFallback).
+	Confidence int
+	// BOM is the length of the BOM, 0 if none.
+	BOM int
+}
+
+// detect returns the detection of the encoding of an input that starts with beginning,
+// the samples of the input being used for the detection (see WithSampling).
+// The BOM is searched in beginning, then the hint, if any, is checked (see WithHint),
+// the charset of the samples is detected, and finally the fallback, if any,
+// replaces an inconclusive detection (see WithFallback).
+// If eof is true, the samples are the end of the input, so their last rune is not truncated.
+// The steps are reported to the tracer of p.
+func detect(beginning, samples []byte, eof bool, p *readerParams) Detection {
+	trace := p.trace
+	if len(beginning) == 0 {
+		return Detection{}
+	}
+	if bom, lb := detectBOM(beginning); bom != "" {
+		trace.emit("bom", "byte order mark found", slog.String("encoding", bom), slog.Int("length", lb))
+		return Detection{Encoding: bom, Confidence: 100, BOM: lb}
+	}
+	trace.emit("bom", "no byte order mark")
+	if p.hint != "" {
+		if encoding, ok := hintedCharset(samples, eof, p.hint, trace); ok {
+			return Detection{Encoding: encoding, Confidence: 100}
+		}
+	}
+	encoding, confidence := detectCharsetTrace(samples, eof, trace)
+	if p.fallback != "" && (encoding == "" || confidence < p.confidence) && !isASCII(samples) {
+		trace.emit("fallback", "the detection is not conclusive, the fallback charset is used",
+			slog.String("detected", encoding), slog.Int("confidence", confidence), slog.String("charset", p.fallback))
+		return Detection{Encoding: p.fallback}
+	}
+	return Detection{Encoding: encoding, Confidence: confidence}
+}
+
+// Detect returns the detected encoding of b, a whole input
+// (a short one, like a database field or a file name, is fine).
+func Detect(b []byte) Detection {
+	return detect(b, b, true, &readerParams{})
+}
+
+// DecodeBytes converts b, a whole input, to UTF-8 like a Reader with the options would,
+// and returns the result with the detection. Unlike a Reader, it uses no peek buffer,
+// so it is suited to the short inputs. The options that concern the streams
+// (the peek, the sampling, the lazy detection, the limits and the hooks) are ignored.
+// It returns an error if some option is invalid.
+func DecodeBytes(b []byte, options ...Option) ([]byte, Detection, error) {
+	p := newParams(options...)
+	if p.err != nil {
+		return nil, Detection{}, p.err
+	}
+	det := detect(b, b, true, p)
+	b = b[det.BOM:]
+	t := p.transformer(det.Encoding)
+	if t == nil {
+		return bytes.Clone(b), det, nil
+	}
+	out, _, err := transform.Bytes(t, b)
+	return out, det, err
+}
+
+// DecodeString is like DecodeBytes for a string.
+func DecodeString(s string, options ...Option) (string, Detection, error) {
+	out, det, err := DecodeBytes([]byte(s), options...)
+	return string(out), det, err
+}
@@ -0,0 +1,82 @@
+package utf8reader
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Detection
+	}{
+		{"", Detection{}},
+		{"plain", Detection{Encoding: "UTF-8"}},
+		{"café", Detection{Encoding: "UTF-8", Confidence: 100}},
+		{"\xef\xbb\xbfcafé", Detection{Encoding: "UTF-8", Confidence: 100, BOM: 3}},
+		{"\xff\xfec\x00a\x00", Detection{Encoding: "UTF-16LE", Confidence: 100, BOM: 2}},
+	}
+	for _, tt := range tests {
+		if got := Detect([]byte(tt.in)); got != tt.want {
+			t.Errorf("Detect(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	in, _ := lookup("windows-1251").NewEncoder().String(strings.Repeat("Това е текст на български език.\n", 20))
+	if got := Detect([]byte(in)); got.Encoding != "windows-1251" || got.Confidence == 0 {
+		t.Errorf("Detect(windows-1251) = %+v, want windows-1251", got)
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	tests := []struct {
+		in      string
+		options []Option
+		want    string
+		enc     string
+	}{
+		{"plain", nil, "plain", "UTF-8"},
+		{"\xef\xbb\xbfcafé", nil, "café", "UTF-8"},
+		{"\xfe\xff\x00c\x00a\x00f\x00\xe9", nil, "café", "UTF-16BE"},
+		{"\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff", []Option{WithHint("windows-1251")}, "България", "windows-1251"},
+		{"\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff", []Option{WithHint("windows-1251"), WithTransliteration("bg")}, "Balgaria", "windows-1251"},
+		{"Café", []Option{WithNormalization("NFC")}, "Café", "UTF-8"},
+		{"", nil, "", ""},
+	}
+	for _, tt := range tests {
+		got, det, err := DecodeBytes([]byte(tt.in), tt.options...)
+		if err != nil || string(got) != tt.want || det.Encoding != tt.enc {
+			t.Errorf("DecodeBytes(%q) = %q, %+v, %v, want %q, %s, nil", tt.in, got, det, err, tt.want, tt.enc)
+		}
+		s, det, err := DecodeString(tt.in, tt.options...)
+		if err != nil || s != tt.want || det.Encoding != tt.enc {
+			t.Errorf("DecodeString(%q) = %q, %+v, %v, want %q, %s, nil", tt.in, s, det, err, tt.want, tt.enc)
+		}
+	}
+
+	// the result does not share the input
+	in := []byte("plain")
+	out, _, _ := DecodeBytes(in)
+	in[0] = 'P'
+	if string(out) != "plain" {
+		t.Errorf("DecodeBytes() shares the input: %q", out)
+	}
+
+	if _, _, err := DecodeBytes([]byte("plain"), WithNormalization("NFX")); err == nil {
+		t.Errorf("DecodeBytes(..., WithNormalization(\"NFX\")) error = nil, want non nil")
+	}
+}
+
+func BenchmarkDecodeString(b *testing.B) {
+	for _, tt := range []struct{ name, in string }{
+		{"ASCII", "plain ASCII field"},
+		{"UTF-8", "Това е поле"},
+		{"windows-1251", "\xd2\xee\xe2\xe0 \xe5 \xef\xee\xeb\xe5"},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				DecodeString(tt.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package utf8reader
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// Detector detects a single encoding for many short samples of the same origin,
+// like the rows of a database column, file names or subject lines,
+// where the detection of each sample alone is unreliable.
+// The samples are added with Add, the consensus encoding is returned by Detection,
+// and the samples are converted with it by Decode.
+// A Detector is not safe for concurrent use.
+type Detector struct {
+	params  *readerParams
+	samples int    // the number of added samples
+	data    []byte // the non-ASCII samples, without their BOM, separated by new lines
+	det     *Detection
+
+	enc string                // the encoding of t
+	t   transform.Transformer // the decoder chained with the transformers, nil if none
+}
+
+// NewDetector returns a Detector with the options.
+// The hint, the fallback (see WithHint and WithFallback) and the transforms are used, the options that concern
+// the streams (the peek, the sampling, the lazy detection, the limits and the hooks) are ignored.
+// It returns an error if some option is invalid.
+func NewDetector(options ...Option) (*Detector, error) {
+	p := newParams(options...)
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Detector{params: p}, nil
+}
+
+// Add adds a sample to the detection.
+// The ASCII samples are compatible with any encoding and only counted.
+// Once MaxPeekSize bytes of non-ASCII samples were collected,
+// the next samples are counted but not examined.
+func (d *Detector) Add(sample []byte) {
+	d.samples++
+	d.det = nil
+	if _, lb := detectBOM(sample); lb > 0 {
+		sample = sample[lb:]
+	}
+	if isASCII(sample) || len(d.data)+len(sample)+1 > MaxPeekSize {
+		return
+	}
+	if len(d.data) > 0 {
+		d.data = append(d.data, '\n')
+	}
+	d.data = append(d.data, sample...)
+}
+
+// AddString is like Add for a string.
+func (d *Detector) AddString(sample string) {
+	d.Add([]byte(sample))
+}
+
+// Samples returns the number of added samples.
+func (d *Detector) Samples() int {
+	return d.samples
+}
+
+// Detection returns the consensus encoding of all the samples added so far.
+// If no sample was added, the encoding is empty, and if all the samples are ASCII,
+// it is "UTF-8" with a zero confidence. The BOM length is always 0.
+func (d *Detector) Detection() Detection {
+	if d.det == nil {
+		var det Detection
+		switch {
+		case len(d.data) > 0:
+			det = detect(d.data, d.data, true, d.params)
+			det.BOM = 0
+		case d.samples > 0:
+			det = Detection{Encoding: "UTF-8"}
+		}
+		d.det = &det
+	}
+	return *d.det
+}
+
+// Decode converts b, usually a sample, to UTF-8 with the consensus encoding
+// and the transforms. If b starts with a BOM, the BOM is stripped
+// and its encoding is used instead. If the consensus encoding is unknown,
+// b is returned unchanged (as a copy).
+func (d *Detector) Decode(b []byte) ([]byte, error) {
+	enc := d.Detection().Encoding
+	if bom, lb := detectBOM(b); bom != "" {
+		enc, b = bom, b[lb:]
+	}
+	if d.t == nil || d.enc != enc {
+		d.enc, d.t = enc, d.params.transformer(enc)
+	}
+	if d.t == nil {
+		return bytes.Clone(b), nil
+	}
+	out, _, err := transform.Bytes(d.t, b)
+	return out, err
+}
+
+// DecodeString is like Decode for a string.
+func (d *Detector) DecodeString(s string) (string, error) {
+	out, err := d.Decode([]byte(s))
+	return string(out), err
+}
@@ -0,0 +1,77 @@
+package utf8reader
+
+import (
+	"testing"
+)
+
+func TestDetector(t *testing.T) {
+	rows := []string{
+		"Иван Петров",
+		"ул. Шипка 12",
+		"София",
+		"Мария Георгиева",
+		"бул. Витоша 45",
+		"Пловдив",
+		"12345",
+		"Стефан Димитров",
+		"ул. Раковски 3",
+		"Варна",
+		"Това е бележка към поръчката.",
+		"Доставката е до офис на куриер.",
+		"Моля, обадете се преди доставката.",
+	}
+	enc := lookup("windows-1251").NewEncoder()
+	d, err := NewDetector()
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	if got := d.Detection(); got != (Detection{}) {
+		t.Errorf("Detection() without samples = %+v, want zero", got)
+	}
+	encoded := make([]string, len(rows))
+	for i, row := range rows {
+		encoded[i], _ = enc.String(row)
+		d.AddString(encoded[i])
+	}
+	if d.Samples() != len(rows) {
+		t.Errorf("Samples() = %d, want %d", d.Samples(), len(rows))
+	}
+	if got := d.Detection(); got.Encoding != "windows-1251" || got.Confidence == 0 {
+		t.Fatalf("Detection() = %+v, want windows-1251", got)
+	}
+	for i, row := range encoded {
+		got, err := d.DecodeString(row)
+		if err != nil || got != rows[i] {
+			t.Errorf("DecodeString(%q) = %q, %v, want %q, nil", row, got, err, rows[i])
+		}
+	}
+	// a sample with a BOM uses its own encoding
+	if got, err := d.DecodeString("\xef\xbb\xbfСофия"); err != nil || got != "София" {
+		t.Errorf("DecodeString(BOM) = %q, %v, want %q, nil", got, err, "София")
+	}
+}
+
+func TestDetector_options(t *testing.T) {
+	d, err := NewDetector(WithHint("koi8-r"), WithTransliteration("ru"))
+	if err != nil {
+		t.Fatalf("NewDetector() error = %v", err)
+	}
+	if got := d.Detection(); got != (Detection{}) {
+		t.Errorf("Detection() without samples = %+v, want zero", got)
+	}
+	d.AddString("plain")
+	if got := d.Detection(); got != (Detection{Encoding: "UTF-8"}) {
+		t.Errorf("Detection() of ASCII samples = %+v, want UTF-8", got)
+	}
+	d.AddString("\xf0\xd2\xc9\xd7\xc5\xd4") // Привет in koi8-r
+	if got := d.Detection(); got != (Detection{Encoding: "koi8-r", Confidence: 100}) {
+		t.Errorf("Detection() with hint = %+v, want koi8-r", got)
+	}
+	if got, err := d.DecodeString("\xf0\xd2\xc9\xd7\xc5\xd4"); err != nil || got != "Privet" {
+		t.Errorf("DecodeString() = %q, %v, want %q, nil", got, err, "Privet")
+	}
+
+	if _, err := NewDetector(WithNormalization("NFX")); err == nil {
+		t.Errorf("NewDetector(WithNormalization(\"NFX\")) error = nil, want non nil")
+	}
+}
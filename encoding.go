@@ -1,9 +1,17 @@
 package utf8reader
 
 import (
+	"encoding/binary"
+	"log/slog"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/gogs/chardet"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode/utf32"
 )
 
 // detectBOM returns the encoding ans the length of the BOM.
@@ -12,18 +20,105 @@ func detectBOM(data []byte) (string, int) {
 	switch {
 	case len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF:
 		return "UTF-8", 3
+	case len(data) >= 4 && data[0] == 0 && data[1] == 0 && data[2] == 0xFE && data[3] == 0xFF:
+		return "UTF-32BE", 4
+	case len(data) >= 4 && data[0] == 0xFF && data[1] == 0xFE && data[2] == 0 && data[3] == 0:
+		return "UTF-32LE", 4
 	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
 		return "UTF-16BE", 2
 	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
 		return "UTF-16LE", 2
-	case len(data) >= 3 && data[0] == 0 && data[1] == 0xFE && data[2] == 0xFF:
-		return "UTF-32BE", 3
-	case len(data) >= 4 && data[0] == 0 && data[1] == 0 && data[2] == 0xFE && data[3] == 0xFF:
-		return "UTF-32LE", 4
 	}
 	return "", 0
 }
 
+// lookup returns the encoding named name, or nil if it is unknown.
+// It knows the WHATWG encodings (see charset.Lookup), UTF-32
+// and the DOS code pages 437 and 850 (used by the ZIP archives).
+func lookup(name string) encoding.Encoding {
+	switch strings.ToUpper(name) {
+	case "UTF-32BE", "UTF-32":
+		return utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM)
+	case "UTF-32LE":
+		return utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM)
+	case "IBM437", "CP437", "437":
+		return charmap.CodePage437
+	case "IBM850", "CP850", "850":
+		return charmap.CodePage850
+	}
+	e, _ := charset.Lookup(name)
+	return e
+}
+
+// hintedCharset returns the encoding of data declared as hint, and true,
+// or false if the hint should be ignored (see WithHint).
+// If eof is true, data is the end of the input (see classify).
+func hintedCharset(data []byte, eof bool, hint string, trace tracer) (string, bool) {
+	if lookup(hint) == nil {
+		trace.emit("hint", "unknown declared charset, ignored", slog.String("charset", hint))
+		return "", false
+	}
+	c := classify(data, eof)
+	_, name := charset.Lookup(hint)
+	switch {
+	case strings.EqualFold(hint, "UTF-8") || name == "utf-8":
+		if !c.utf8 {
+			trace.emit("hint", "declared UTF-8 but invalid UTF-8, ignored", slog.String("charset", hint))
+			return "", false
+		}
+		hint = "UTF-8"
+	case c.utf8 && !c.ascii:
+		trace.emit("hint", "valid UTF-8 with non-ASCII characters, the declared charset is ignored", slog.String("charset", hint))
+		return "UTF-8", true
+	}
+	trace.emit("hint", "declared charset used", slog.String("charset", hint))
+	return hint, true
+}
+
+// canonicalName returns the upper case canonical name of the encoding name,
+// resolving the aliases (like "utf-16" or "unicode" for "UTF-16LE"),
+// or name in upper case if it has no canonical name.
+func canonicalName(name string) string {
+	upper := strings.ToUpper(name)
+	if upper == "UTF-32" {
+		return "UTF-32BE"
+	}
+	if e, err := htmlindex.Get(name); err == nil {
+		if n, err := htmlindex.Name(e); err == nil {
+			return strings.ToUpper(n)
+		}
+	}
+	return upper
+}
+
+// unitSize returns the size in bytes of the code units of the encoding:
+// 1 for the single byte encodings, 2 for UTF-16 and 4 for UTF-32,
+// or 0 if the encoding has variable length characters (like UTF-8 or Shift_JIS).
+func unitSize(name string) int {
+	switch canonical := canonicalName(name); {
+	case strings.HasPrefix(canonical, "UTF-16"):
+		return 2
+	case strings.HasPrefix(canonical, "UTF-32"):
+		return 4
+	}
+	e, err := htmlindex.Get(name)
+	if err != nil {
+		// like the DOS code pages
+		e = lookup(name)
+	}
+	if _, ok := e.(*charmap.Charmap); ok {
+		return 1
+	}
+	return 0
+}
+
+// isStateful returns true if the decoder of the encoding is stateful,
+// i.e. the meaning of the bytes depends on the previous escape sequences.
+func isStateful(name string) bool {
+	canonical := canonicalName(name)
+	return strings.HasPrefix(canonical, "ISO-2022-") || canonical == "HZ-GB-2312"
+}
+
 // trunc returns the length without possibly the last truncated rune.
 func trunc(data []byte) int {
 	end := len(data)
@@ -50,6 +145,20 @@ func isUTF8(data []byte) bool {
 	return utf8.Valid(data[:trunc(data)])
 }
 
+// countNulPairs returns the number of <null><ascii> pairs (for UTF-16 BE)
+// and <ascii><null> pairs (for UTF-16 LE) at even positions.
+func countNulPairs(data []byte) (utf16be, utf16le int) {
+	for i := 0; i < len(data)-1; i += 2 {
+		if data[i] == 0 && data[i+1] < 128 {
+			utf16be++
+		}
+		if data[i] < 128 && data[i+1] == 0 {
+			utf16le++
+		}
+	}
+	return utf16be, utf16le
+}
+
 // guessUTF16 returns the "UTF-16 LE", "UTF-16 BE" if it looks like a valid UTF-16.
 // - if no bom is found it counts the number of
 //   - <null><ascii> pairs (for UTF-16 BE)
@@ -59,18 +168,12 @@ func isUTF8(data []byte) bool {
 // We need this heuristic because chardet does not always detect UTF-16 correctly.
 // For example, if the text is an ascii encoded as UTF-16 it will detect it as ASCII.
 func guessUTF16(data []byte) string {
-	utf16be := 0
-	for i := 0; i < len(data)-1; i += 2 {
-		if data[i] == 0 && data[i+1] < 128 {
-			utf16be++
-		}
-	}
-	utf16le := 0
-	for i := 0; i < len(data)-1; i += 2 {
-		if data[i] < 128 && data[i+1] == 0 {
-			utf16le++
-		}
-	}
+	return pickUTF16(countNulPairs(data))
+}
+
+// pickUTF16 returns the UTF-16 variant suggested by the counts of <null><ascii>
+// and <ascii><null> pairs, or "" if there is no such pair.
+func pickUTF16(utf16be, utf16le int) string {
 	if utf16be > 0 || utf16le > 0 {
 		if utf16be > utf16le {
 			return "UTF-16BE"
@@ -81,19 +184,137 @@ func guessUTF16(data []byte) string {
 	return ""
 }
 
+// isASCII returns true if all the bytes of data are ASCII.
+func isASCII(data []byte) bool {
+	return asciiPrefix(data) == len(data)
+}
+
+const (
+	lowBits  = 0x0101010101010101
+	highBits = 0x8080808080808080
+)
+
+// asciiPrefix returns the length of the longest ASCII prefix of data.
+// It checks 8 bytes at a time.
+func asciiPrefix(data []byte) int {
+	i := 0
+	for ; i+8 <= len(data); i += 8 {
+		if binary.LittleEndian.Uint64(data[i:])&highBits != 0 {
+			break
+		}
+	}
+	for ; i < len(data); i++ {
+		if data[i] >= utf8.RuneSelf {
+			break
+		}
+	}
+	return i
+}
+
+// class is the result of the classification of a sample.
+type class struct {
+	ascii   bool // the data is ASCII, except possibly the last truncated rune (if not at the end of the input)
+	utf8    bool // the data is valid UTF-8, except possibly the last truncated rune (if not at the end of the input)
+	utf16be int  // the number of <null><ascii> pairs at even positions
+	utf16le int  // the number of <ascii><null> pairs at even positions
+	pairs   int  // the number of pairs at even positions
+}
+
+// classify returns the class of data. The ASCII prefix is checked
+// 8 bytes at a time, and the null pairs are only counted in the words
+// containing a null byte. The rest of the data (after the first non-ASCII byte)
+// is validated as UTF-8 and the null pairs are counted.
+// If eof is false, the last rune may be truncated and is ignored,
+// otherwise data is the end of the input and all its bytes are checked.
+func classify(data []byte, eof bool) class {
+	c := class{pairs: len(data) / 2}
+	end := len(data)
+	if !eof {
+		end = trunc(data)
+	}
+	i := 0
+	for ; i+8 <= end; i += 8 {
+		w := binary.LittleEndian.Uint64(data[i:])
+		if w&highBits != 0 {
+			break
+		}
+		if (w-lowBits)&^w&highBits != 0 {
+			// some byte is null
+			be, le := countNulPairs(data[i : i+8])
+			c.utf16be += be
+			c.utf16le += le
+		}
+	}
+	ascii := asciiPrefix(data[i:end])
+	be, le := countNulPairs(data[i:])
+	c.utf16be += be
+	c.utf16le += le
+	c.ascii = i+ascii == end
+	c.utf8 = c.ascii || utf8.Valid(data[i+ascii:end])
+	return c
+}
+
 // detectCharset returns the encoding of the data.
-// if the data is Ascii it returns an empty string.
+// if the data is Ascii it returns "UTF-8".
 func detectCharset(data []byte) string {
-	if isUTF8(data) {
-		return "UTF-8"
+	encoding, _ := detectCharsetConfidence(data)
+	return encoding
+}
+
+// detectCharsetConfidence returns the encoding of the data
+// and the confidence of the detection, between 0 and 100.
+// If the data is Ascii (except possibly the last truncated rune)
+// it returns "UTF-8" with a zero confidence,
+// because the data can be in any ASCII compatible encoding.
+// The obvious cases are decided by a single pass classification,
+// and chardet is used only for the other ones.
+func detectCharsetConfidence(data []byte) (string, int) {
+	return detectCharsetTrace(data, false, nil)
+}
+
+// maxCandidates is the number of chardet candidates reported in the trace.
+const maxCandidates = 5
+
+// detectCharsetTrace is detectCharsetConfidence that reports the steps to trace.
+// If eof is true, data is the end of the input, so its last rune is not truncated.
+func detectCharsetTrace(data []byte, eof bool, trace tracer) (string, int) {
+	c := classify(data, eof)
+	if trace != nil {
+		trace.emit("classify", "single pass classification",
+			slog.Int("bytes", len(data)), slog.Bool("ascii", c.ascii), slog.Bool("utf8", c.utf8),
+			slog.Int("utf16be", c.utf16be), slog.Int("utf16le", c.utf16le), slog.Int("pairs", c.pairs))
+	}
+	decide := func(encoding string, confidence int, reason string) (string, int) {
+		if trace != nil {
+			trace.emit("decision", reason, slog.String("encoding", encoding), slog.Int("confidence", confidence))
+		}
+		return encoding, confidence
+	}
+	if c.utf8 {
+		if c.ascii {
+			return decide("UTF-8", 0, "ASCII, compatible with any ASCII based encoding")
+		}
+		return decide("UTF-8", 100, "valid UTF-8 with non-ASCII characters")
 	}
-	if encoding := guessUTF16(data); encoding != "" {
-		return encoding
+	if encoding := pickUTF16(c.utf16be, c.utf16le); encoding != "" {
+		return decide(encoding, 100, "invalid UTF-8 with null pairs")
 	}
 	detector := chardet.NewTextDetector()
-	result, err := detector.DetectBest(data)
-	if err != nil {
-		return ""
+	if trace == nil {
+		result, err := detector.DetectBest(data)
+		if err != nil {
+			return "", 0
+		}
+		return result.Charset, result.Confidence
+	}
+	results, err := detector.DetectAll(data)
+	if err != nil || len(results) == 0 {
+		return decide("", 0, "no chardet candidate")
+	}
+	attrs := make([]slog.Attr, 0, maxCandidates)
+	for _, r := range results[:min(len(results), maxCandidates)] {
+		attrs = append(attrs, slog.Int(r.Charset, r.Confidence))
 	}
-	return result.Charset
+	trace.emit("chardet", "candidates ranked by confidence", attrs...)
+	return decide(results[0].Charset, results[0].Confidence, "best chardet candidate")
 }
@@ -78,3 +78,101 @@ func TestGuessUTF16(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectCharsetConfidence(t *testing.T) {
+	data := []struct {
+		in         []byte
+		encoding   string
+		confidence int
+	}{
+		// ascii
+		{[]byte("mouille"), "UTF-8", 0},
+		// truncated "mouillé" is ascii so far
+		{[]byte{0x6d, 0x6f, 0x75, 0x69, 0x6c, 0x6c, 0xc3}, "UTF-8", 0},
+		// fully encoded "mouillé"
+		{[]byte{0x6d, 0x6f, 0x75, 0x69, 0x6c, 0x6c, 0xc3, 0xa9}, "UTF-8", 100},
+		// UTF-16LE "bétà"
+		{[]byte{0x62, 0x00, 0xe9, 0x00, 0x74, 0x00, 0xe0, 0x00}, "UTF-16LE", 100},
+	}
+	for _, d := range data {
+		encoding, confidence := detectCharsetConfidence(d.in)
+		if encoding != d.encoding || confidence != d.confidence {
+			t.Errorf("detectCharsetConfidence(%v) = %s, %d, want %s, %d", d.in, encoding, confidence, d.encoding, d.confidence)
+		}
+	}
+}
+
+func TestDetectBOM(t *testing.T) {
+	data := []struct {
+		in       []byte
+		encoding string
+		length   int
+	}{
+		{[]byte{0xEF, 0xBB, 0xBF, 0x61}, "UTF-8", 3},
+		{[]byte{0xFE, 0xFF, 0x00, 0x61}, "UTF-16BE", 2},
+		{[]byte{0xFF, 0xFE, 0x61, 0x00}, "UTF-16LE", 2},
+		{[]byte{0x00, 0x00, 0xFE, 0xFF, 0x00, 0x00, 0x00, 0x61}, "UTF-32BE", 4},
+		{[]byte{0xFF, 0xFE, 0x00, 0x00, 0x61, 0x00, 0x00, 0x00}, "UTF-32LE", 4},
+		{[]byte{0x61, 0x62}, "", 0},
+	}
+	for _, d := range data {
+		if encoding, length := detectBOM(d.in); encoding != d.encoding || length != d.length {
+			t.Errorf("detectBOM(% X) = %s, %d, want %s, %d", d.in, encoding, length, d.encoding, d.length)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	data := []struct {
+		in  []byte
+		out class
+	}{
+		{[]byte("0123456789abcdef"), class{ascii: true, utf8: true, pairs: 8}},
+		{[]byte("0123456789abcdé"), class{utf8: true, pairs: 8}},
+		{[]byte("0123456789abcd\xe9"), class{ascii: true, utf8: true, pairs: 7}},
+		{[]byte("0123456789abcd\xe9 "), class{pairs: 8}},
+		{[]byte("a\x00b\x00c\x00d\x00e\x00"), class{ascii: true, utf8: true, utf16le: 5, pairs: 5}},
+		{[]byte("\x00a\x00b\x00c\x00d\x00\xe9"), class{ascii: true, utf8: true, utf16be: 4, pairs: 5}},
+		{nil, class{ascii: true, utf8: true}},
+	}
+	for _, d := range data {
+		if got := classify(d.in, false); got != d.out {
+			t.Errorf("classify(%q) = %+v, want %+v", d.in, got, d.out)
+		}
+	}
+	// at the end of the input the last byte is not a truncated rune
+	if got := classify([]byte("0123456789abcd\xe9"), true); got != (class{pairs: 7}) {
+		t.Errorf("classify(..., true) = %+v, want %+v", got, class{pairs: 7})
+	}
+}
+
+func TestUnitSize(t *testing.T) {
+	data := []struct {
+		name string
+		unit int
+	}{
+		{"UTF-16LE", 2}, {"utf-16", 2}, {"unicode", 2}, {"unicodefffe", 2},
+		{"utf-32", 4}, {"UTF-32LE", 4},
+		{"latin1", 1}, {"koi8-r", 1}, {"cp437", 1},
+		{"UTF-8", 0}, {"Shift_JIS", 0}, {"unknown", 0},
+	}
+	for _, d := range data {
+		if got := unitSize(d.name); got != d.unit {
+			t.Errorf("unitSize(%q) = %d, want %d", d.name, got, d.unit)
+		}
+	}
+	if !isStateful("csISO2022JP") || isStateful("UTF-8") {
+		t.Errorf("isStateful(csISO2022JP), isStateful(UTF-8) = %t, %t, want true, false", isStateful("csISO2022JP"), isStateful("UTF-8"))
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"utf-8", "UTF-32LE", "windows-1251", "latin1", "cp866", "cp437", "IBM850"} {
+		if lookup(name) == nil {
+			t.Errorf("lookup(%q) = nil, want an encoding", name)
+		}
+	}
+	if e := lookup("unknown"); e != nil {
+		t.Errorf("lookup(\"unknown\") = %v, want nil", e)
+	}
+}
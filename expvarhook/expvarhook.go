@@ -0,0 +1,56 @@
+// Package expvarhook exports the statistics of the utf8reader Readers as expvar variables.
+//
+// It is a separate package because importing expvar registers
+// the /debug/vars handler on http.DefaultServeMux.
+package expvarhook
+
+import (
+	"expvar"
+	"io"
+	"time"
+
+	"github.com/kpym/utf8reader"
+)
+
+// Hook is a utf8reader.Hook that adds the statistics of the Readers to an expvar.Map:
+//   - "encodings" is a map of the number of Readers by detected encoding ("unknown" if none),
+//   - "detection_ns" is the total detection time in nanoseconds,
+//   - "bytes_in", "bytes_out", "runes", "replacements" and "lines" are the totals
+//     of the Readers read until the end or an error,
+//   - "errors" is the number of Readers stopped by an error other than io.EOF.
+type Hook struct {
+	m         *expvar.Map
+	encodings *expvar.Map
+}
+
+// New returns a Hook that adds the statistics to m.
+// For example:
+//
+//	hook := expvarhook.New(expvar.NewMap("utf8reader"))
+//	r, err := utf8reader.NewReader(body, utf8reader.WithHook(hook))
+func New(m *expvar.Map) *Hook {
+	encodings := new(expvar.Map)
+	m.Set("encodings", encodings)
+	return &Hook{m: m, encodings: encodings}
+}
+
+// Detected implements the utf8reader.Hook interface.
+func (h *Hook) Detected(encoding string, d time.Duration) {
+	if encoding == "" {
+		encoding = "unknown"
+	}
+	h.encodings.Add(encoding, 1)
+	h.m.Add("detection_ns", int64(d))
+}
+
+// Done implements the utf8reader.Hook interface.
+func (h *Hook) Done(s utf8reader.Stats, err error) {
+	h.m.Add("bytes_in", s.BytesIn)
+	h.m.Add("bytes_out", s.BytesOut)
+	h.m.Add("runes", s.Runes)
+	h.m.Add("replacements", s.Replacements)
+	h.m.Add("lines", s.Lines)
+	if err != io.EOF {
+		h.m.Add("errors", 1)
+	}
+}
@@ -0,0 +1,40 @@
+package expvarhook
+
+import (
+	"expvar"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kpym/utf8reader"
+)
+
+func TestHook(t *testing.T) {
+	m := new(expvar.Map)
+	h := New(m)
+	for _, in := range []string{"caf\xe9 cr\xe8me br\xfbl\xe9e au caf\xe9\n", "plain text\n"} {
+		r, err := utf8reader.NewReader(strings.NewReader(in), utf8reader.WithHook(h))
+		if err != nil {
+			t.Fatalf("NewReader() error = %v, want nil", err)
+		}
+		if _, err := io.ReadAll(r); err != nil {
+			t.Fatalf("io.ReadAll(r) error = %v, want nil", err)
+		}
+	}
+	for name, want := range map[string]string{
+		"bytes_in":  "37",
+		"bytes_out": "42",
+		"runes":     "37",
+		"lines":     "2",
+	} {
+		if got := m.Get(name); got == nil || got.String() != want {
+			t.Errorf("m.Get(%q) = %v, want %s", name, got, want)
+		}
+	}
+	if got := m.Get("errors"); got != nil {
+		t.Errorf("m.Get(\"errors\") = %v, want nil", got)
+	}
+	if got := m.Get("encodings").String(); !strings.Contains(got, `"UTF-8": 1`) {
+		t.Errorf("encodings = %s, want a single UTF-8", got)
+	}
+}
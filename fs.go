@@ -0,0 +1,173 @@
+package utf8reader
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+// FS returns a file system that serves the text files of fsys converted to UTF-8 (see New),
+// and the directories and the binary files unchanged. A file is binary if its first
+// bytes contain a null byte (except for UTF-16 or UTF-32 text) or too many control characters.
+// The files are streamed. The size of the binary files and of the UTF-8 files
+// without BOM (that need no conversion) is the original one. The size of the converted files
+// is computed when the Size method of their FileInfo (from Stat, or from the Info method
+// of the directory entries) is first called, by converting the whole file once more.
+func FS(fsys fs.FS, options ...Option) fs.FS {
+	return &utf8FS{fsys: fsys, options: options}
+}
+
+// utf8FS is the file system returned by FS.
+type utf8FS struct {
+	fsys    fs.FS
+	options []Option
+}
+
+// Open opens the named file, converted to UTF-8 if it is a text file.
+func (u *utf8FS) Open(name string) (fs.File, error) {
+	f, err := u.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		if d, ok := f.(fs.ReadDirFile); ok {
+			return &dirFile{ReadDirFile: d, fsys: u, name: name}, nil
+		}
+		return f, nil
+	}
+	r, err := NewReader(f, u.options...)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if r.bom == 0 && isBinary(r.buf, r.Encoding()) {
+		// the peeked bytes followed by the rest of the file
+		return &file{File: f, r: io.MultiReader(bytes.NewReader(r.buf), f), info: info}, nil
+	}
+	if r.t == nil && r.bom == 0 && r.lazy == nil {
+		// UTF-8 without BOM nor transforms: the size is unchanged
+		return &file{File: f, r: r, info: info}, nil
+	}
+	return &file{File: f, r: r, info: &fileInfo{FileInfo: info, fsys: u, name: name}}, nil
+}
+
+// ReadDir reads the named directory. The Info method of the entries
+// returns the converted sizes (see FS).
+func (u *utf8FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(u.fsys, name)
+	return u.wrapEntries(name, entries), err
+}
+
+// wrapEntries wraps the entries of the directory dir.
+func (u *utf8FS) wrapEntries(dir string, entries []fs.DirEntry) []fs.DirEntry {
+	for i, e := range entries {
+		if !e.IsDir() {
+			entries[i] = &dirEntry{DirEntry: e, fsys: u, name: path.Join(dir, e.Name())}
+		}
+	}
+	return entries
+}
+
+// size returns the size of the named file served by u, by reading it.
+func (u *utf8FS) size(name string) (int64, error) {
+	f, err := u.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(io.Discard, f)
+}
+
+// isBinary returns true if the first bytes of a file, without BOM, look binary:
+// a null byte, except in UTF-16 or UTF-32 text (with no null code unit),
+// or more than 10% of control characters.
+func isBinary(data []byte, encoding string) bool {
+	if unitSize(encoding) > 1 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	controls := 0
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1b || b == 0x7f {
+			controls++
+		}
+	}
+	return 10*controls > len(data)
+}
+
+// file is a file streamed from r, with the FileInfo of the original file.
+type file struct {
+	fs.File
+	r    io.Reader
+	info fs.FileInfo
+}
+
+// Read reads the streamed content.
+func (f *file) Read(p []byte) (int, error) { return f.r.Read(p) }
+
+// Stat returns the FileInfo of the file.
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// fileInfo is the FileInfo of a converted file, whose size is computed on demand.
+type fileInfo struct {
+	fs.FileInfo
+	fsys *utf8FS
+	name string
+	once sync.Once
+	size int64
+}
+
+// Size returns the size of the converted content, computed at the first call.
+// If the file can not be converted, it returns the size of the original file.
+func (fi *fileInfo) Size() int64 {
+	fi.once.Do(func() {
+		var err error
+		if fi.size, err = fi.fsys.size(fi.name); err != nil {
+			fi.size = fi.FileInfo.Size()
+		}
+	})
+	return fi.size
+}
+
+// dirFile is a directory with wrapped entries.
+type dirFile struct {
+	fs.ReadDirFile
+	fsys *utf8FS
+	name string
+}
+
+// ReadDir reads the wrapped entries of the directory.
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries, err := d.ReadDirFile.ReadDir(n)
+	return d.fsys.wrapEntries(d.name, entries), err
+}
+
+// dirEntry is a directory entry whose Info reports the converted size.
+type dirEntry struct {
+	fs.DirEntry
+	fsys *utf8FS
+	name string
+}
+
+// Info returns the FileInfo of the entry, whose size is computed on demand.
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{FileInfo: info, fsys: e.fsys, name: e.name}, nil
+}
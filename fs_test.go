@@ -0,0 +1,79 @@
+package utf8reader
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+)
+
+// testFS returns a file system with text files in several encodings and a binary file.
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"utf8.txt":            {Data: []byte("café\n")},
+		"latin1.txt":          {Data: []byte("caf\xe9 cr\xe8me br\xfbl\xe9e\n")},
+		"bom.txt":             {Data: []byte("\xef\xbb\xbfcafé\n")},
+		"utf16.txt":           {Data: []byte("\xff\xfec\x00a\x00f\x00\xe9\x00\n\x00")},
+		"image.png":           {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xe9")},
+		"tmpl/hello.tmpl":     {Data: []byte("{{define \"hello\"}}Bonjour {{.}}, un caf\xe9 ?{{end}}")},
+		"tmpl/sub/empty.tmpl": {Data: []byte("")},
+	}
+}
+
+func TestFS(t *testing.T) {
+	fsys := FS(testFS())
+	tests := map[string]string{
+		"utf8.txt":   "café\n",
+		"latin1.txt": "café crème brûlée\n",
+		"bom.txt":    "café\n",
+		"utf16.txt":  "café\n",
+		"image.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xe9",
+	}
+	for name, want := range tests {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil || string(b) != want {
+			t.Errorf("fs.ReadFile(fsys, %q) = %q, %v, want %q, nil", name, b, err, want)
+		}
+		// the size of the converted content
+		fi, err := fs.Stat(fsys, name)
+		if err != nil || fi.Size() != int64(len(want)) {
+			t.Errorf("fs.Stat(fsys, %q).Size() = %v, %v, want %d", name, fi.Size(), err, len(want))
+		}
+		entries, _ := fs.ReadDir(fsys, ".")
+		for _, e := range entries {
+			if e.Name() == name {
+				if fi, err = e.Info(); err != nil || fi.Size() != int64(len(want)) {
+					t.Errorf("%q Info().Size() = %v, %v, want %d", name, fi.Size(), err, len(want))
+				}
+			}
+		}
+	}
+
+	if err := fstest.TestFS(fsys, "utf8.txt", "latin1.txt", "image.png", "tmpl/hello.tmpl"); err != nil {
+		t.Errorf("fstest.TestFS(fsys) error = %v, want nil", err)
+	}
+}
+
+func TestFS_walkAndTemplates(t *testing.T) {
+	fsys := FS(testFS())
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, path)
+		}
+		return err
+	})
+	if err != nil || len(names) != 7 {
+		t.Errorf("fs.WalkDir(fsys) = %v, %v, want 7 files", names, err)
+	}
+
+	tmpl, err := template.ParseFS(fsys, "tmpl/*.tmpl")
+	if err != nil {
+		t.Fatalf("template.ParseFS(fsys) error = %v, want nil", err)
+	}
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "hello", "Ivan"); err != nil || b.String() != "Bonjour Ivan, un café ?" {
+		t.Errorf("tmpl.ExecuteTemplate(hello) = %q, %v, want \"Bonjour Ivan, un café ?\", nil", b.String(), err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package httputf8 converts the text bodies of the HTTP requests and responses to UTF-8.
+//
+// Handler is a server middleware that converts the incoming request bodies,
+// and Transport is a client http.RoundTripper that converts the response bodies.
+// Only the bodies with a text Content-Type (see IsText) and no Content-Encoding are converted.
+// The declared charset is honored (see utf8reader.WithHint), the Content-Type is rewritten
+// with charset=utf-8 and the Content-Length, no longer valid, is dropped.
+//
+// The encoding is detected at the first Read of the body, so the handlers and the clients
+// are not blocked until the first bytes arrive (like with the streamed responses).
+// So the Content-Type is rewritten before the detection: if the detection fails
+// (the first Read returns the error), or if the encoding is unknown
+// (the bytes are passed through unchanged), the charset=utf-8 parameter is wrong.
+package httputf8
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/kpym/utf8reader"
+)
+
+// IsText returns true if the media type (without parameters) is a text type:
+// text/*, application/json, application/xml, application/javascript,
+// or a type with the +json or +xml suffix.
+func IsText(mediatype string) bool {
+	mediatype = strings.ToLower(mediatype)
+	switch mediatype {
+	case "application/json", "application/xml", "application/javascript":
+		return true
+	}
+	return strings.HasPrefix(mediatype, "text/") ||
+		strings.HasSuffix(mediatype, "+json") ||
+		strings.HasSuffix(mediatype, "+xml")
+}
+
+// Handler returns a handler that converts the text request bodies to UTF-8,
+// using the options, before calling next.
+func Handler(next http.Handler, options ...utf8reader.Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header, body := convert(r.Header, r.Body, options); body != nil {
+			r2 := r.Clone(r.Context())
+			r2.Header = header
+			r2.Body = body
+			r2.ContentLength = -1
+			r = r2
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Transport is an http.RoundTripper that converts the text response bodies to UTF-8.
+type Transport struct {
+	// Base is the underlying RoundTripper, http.DefaultTransport if nil.
+	Base http.RoundTripper
+	// Options are the options of the utf8reader.Reader used to convert the bodies.
+	Options []utf8reader.Option
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if header, body := convert(resp.Header, resp.Body, t.Options); body != nil {
+		resp.Header = header
+		resp.Body = body
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
+
+// convert returns a copy of the header with the UTF-8 Content-Type and without Content-Length,
+// and the body converted to UTF-8. It returns nil, nil if the body should not be converted.
+func convert(h http.Header, rc io.ReadCloser, options []utf8reader.Option) (http.Header, io.ReadCloser) {
+	if rc == nil || rc == http.NoBody {
+		return nil, nil
+	}
+	if ce := h.Get("Content-Encoding"); ce != "" && !strings.EqualFold(ce, "identity") {
+		return nil, nil
+	}
+	mediatype, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !IsText(mediatype) {
+		return nil, nil
+	}
+	if charset := params["charset"]; charset != "" {
+		options = append(options[:len(options):len(options)], utf8reader.WithHint(charset))
+	}
+	params["charset"] = "utf-8"
+	header := h.Clone()
+	header.Set("Content-Type", mime.FormatMediaType(mediatype, params))
+	header.Del("Content-Length")
+	return header, &body{rc: rc, options: options}
+}
+
+// body is a body converted to UTF-8.
+// The Reader is created at the first Read, so the peek does not block
+// the handler or the round trip.
+type body struct {
+	rc      io.ReadCloser
+	options []utf8reader.Option
+	r       *utf8reader.Reader
+	err     error // the error of utf8reader.NewReader
+}
+
+// Read reads the UTF-8 converted body.
+func (b *body) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = utf8reader.NewReader(b.rc, b.options...)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+// Close closes the original body.
+func (b *body) Close() error {
+	return b.rc.Close()
+}
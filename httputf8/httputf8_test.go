@@ -0,0 +1,111 @@
+package httputf8
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsText(t *testing.T) {
+	for mediatype, want := range map[string]bool{
+		"text/plain":               true,
+		"Text/HTML":                true,
+		"application/json":         true,
+		"application/atom+xml":     true,
+		"application/problem+json": true,
+		"application/octet-stream": false,
+		"image/png":                false,
+	} {
+		if got := IsText(mediatype); got != want {
+			t.Errorf("IsText(%q) = %t, want %t", mediatype, got, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("io.ReadAll(r.Body) error = %v, want nil", err)
+		}
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Content-Length", r.Header.Get("Content-Length"))
+		w.Write(b)
+	}))
+
+	tests := []struct {
+		contentType string
+		in          string
+		out         string
+		outType     string
+		outLength   string
+	}{
+		{"text/plain; charset=windows-1251", "\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff", "България", "text/plain; charset=utf-8", ""},
+		{"text/plain; charset=ISO-8859-1; format=flowed", "caf\xe9", "café", "text/plain; charset=utf-8; format=flowed", ""},
+		{"text/csv", "caf\xe9 cr\xe8me br\xfbl\xe9e", "café crème brûlée", "text/csv; charset=utf-8", ""},
+		{"application/octet-stream", "caf\xe9", "caf\xe9", "application/octet-stream", "4"},
+		{"", "caf\xe9", "caf\xe9", "", "4"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.in))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("Content-Length", "4")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != tt.out {
+			t.Errorf("%q: body = %q, want %q", tt.contentType, got, tt.out)
+		}
+		if got := rec.Header().Get("X-Content-Type"); got != tt.outType {
+			t.Errorf("%q: Content-Type = %q, want %q", tt.contentType, got, tt.outType)
+		}
+		if got := rec.Header().Get("X-Content-Length"); got != tt.outLength {
+			t.Errorf("%q: Content-Length = %q, want %q", tt.contentType, got, tt.outLength)
+		}
+		if got := req.Header.Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%q: the original request is modified: %q", tt.contentType, got)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			io.WriteString(w, "<p>caf\xe9</p>")
+		case "/binary":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, "\x89PNG\xe9")
+		}
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &Transport{}}
+
+	resp, err := client.Get(srv.URL + "/latin1")
+	if err != nil {
+		t.Fatalf("client.Get(/latin1) error = %v, want nil", err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(b) != "<p>café</p>" {
+		t.Errorf("/latin1 body = %q, %v, want \"<p>café</p>\", nil", b, err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("/latin1 Content-Type = %q, want \"text/html; charset=utf-8\"", ct)
+	}
+	if resp.ContentLength != -1 || resp.Header.Get("Content-Length") != "" {
+		t.Errorf("/latin1 Content-Length = %d, %q, want -1, \"\"", resp.ContentLength, resp.Header.Get("Content-Length"))
+	}
+
+	resp, err = client.Get(srv.URL + "/binary")
+	if err != nil {
+		t.Fatalf("client.Get(/binary) error = %v, want nil", err)
+	}
+	b, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "\x89PNG\xe9" || resp.ContentLength != 5 {
+		t.Errorf("/binary body = %q, %d, want the original body", b, resp.ContentLength)
+	}
+}
@@ -0,0 +1,66 @@
+package utf8reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"golang.org/x/text/transform"
+)
+
+// jsonEncoding returns the encoding of a JSON text that starts with head,
+// following the RFC 4627 (section 3): the first two characters of a JSON text
+// are ASCII, so the pattern of the null bytes in the first four bytes
+// gives the encoding. It returns the length of the BOM, if any (RFC 8259 allows to ignore it).
+//
+//	00 00 00 xx  UTF-32BE
+//	00 xx 00 xx  UTF-16BE
+//	xx 00 00 00  UTF-32LE
+//	xx 00 xx 00  UTF-16LE
+//	xx xx xx xx  UTF-8
+//
+// The shorter texts, like a single digit, are detected on the available bytes.
+func jsonEncoding(head []byte) (string, int) {
+	if bom, lb := detectBOM(head); bom != "" {
+		return bom, lb
+	}
+	switch {
+	case len(head) >= 4 && head[0] == 0 && head[1] == 0 && head[2] == 0:
+		return "UTF-32BE", 0
+	case len(head) >= 4 && head[1] == 0 && head[2] == 0 && head[3] == 0:
+		return "UTF-32LE", 0
+	case len(head) >= 2 && head[0] == 0:
+		return "UTF-16BE", 0
+	case len(head) >= 2 && head[1] == 0:
+		return "UTF-16LE", 0
+	}
+	return "UTF-8", 0
+}
+
+// NewJSONDecoder returns a json.Decoder that reads the JSON text of r
+// in UTF-8, UTF-16 or UTF-32, with or without a BOM, converted to UTF-8.
+// The encoding is detected on the first four bytes (see RFC 4627, section 3),
+// so the legacy 8-bit encodings, not allowed in JSON, are not detected.
+// The transforms of the options are applied, the other options are ignored.
+// It returns an error if some option is invalid, or if the first bytes of r can not be read.
+func NewJSONDecoder(r io.Reader, options ...Option) (*json.Decoder, error) {
+	if r == nil {
+		return nil, errors.New("utf8reader: nil reader")
+	}
+	p := newParams(options...)
+	if p.err != nil {
+		return nil, p.err
+	}
+	var head [4]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	enc, lb := jsonEncoding(head[:n])
+	r = io.MultiReader(bytes.NewReader(head[lb:n]), r)
+	if t := p.transformer(enc); t != nil {
+		r = transform.NewReader(r, t)
+	}
+	return json.NewDecoder(r), nil
+}
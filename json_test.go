@@ -0,0 +1,61 @@
+package utf8reader
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestJSONEncoding(t *testing.T) {
+	tests := []struct {
+		in  string
+		enc string
+		bom int
+	}{
+		{`{"a":1}`, "UTF-8", 0},
+		{"\x00\x00\x00{\x00\x00\x00}", "UTF-32BE", 0},
+		{"{\x00\x00\x00}\x00\x00\x00", "UTF-32LE", 0},
+		{"\x00{\x00}", "UTF-16BE", 0},
+		{"{\x00}\x00", "UTF-16LE", 0},
+		{"\x001", "UTF-16BE", 0},
+		{"1\x00", "UTF-16LE", 0},
+		{"1", "UTF-8", 0},
+		{"", "UTF-8", 0},
+		{"\xef\xbb\xbf{}", "UTF-8", 3},
+		{"\xff\xfe{\x00}\x00", "UTF-16LE", 2},
+		{"\x00\x00\xfe\xff\x00\x00\x00{", "UTF-32BE", 4},
+	}
+	for _, tt := range tests {
+		if enc, bom := jsonEncoding([]byte(tt.in)); enc != tt.enc || bom != tt.bom {
+			t.Errorf("jsonEncoding(%q) = %s, %d, want %s, %d", tt.in, enc, bom, tt.enc, tt.bom)
+		}
+	}
+}
+
+func TestNewJSONDecoder(t *testing.T) {
+	const doc = `{"name": "България", "n": 1}`
+	utf16le, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().String(doc)
+	utf16bom, _ := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().String(doc)
+	utf32be, _ := lookup("UTF-32BE").NewEncoder().String(doc)
+	for _, in := range []string{doc, "\xef\xbb\xbf" + doc, utf16le, utf16bom, utf32be} {
+		d, err := NewJSONDecoder(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("NewJSONDecoder(%q) error = %v", in, err)
+		}
+		var v struct {
+			Name string
+			N    int
+		}
+		if err := d.Decode(&v); err != nil || v.Name != "България" || v.N != 1 {
+			t.Errorf("Decode(%q) = %+v, %v, want {България 1}, nil", in, v, err)
+		}
+	}
+
+	if _, err := NewJSONDecoder(nil); err == nil {
+		t.Errorf("NewJSONDecoder(nil) error = nil, want non nil")
+	}
+	if _, err := NewJSONDecoder(strings.NewReader(doc), WithNormalization("NFX")); err == nil {
+		t.Errorf("NewJSONDecoder(..., WithNormalization(\"NFX\")) error = nil, want non nil")
+	}
+}
@@ -0,0 +1,150 @@
+package utf8reader
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// lazyContext is the number of ASCII bytes before the first non-ASCII byte
+// that are added to the detection window.
+const lazyContext = 1024
+
+// lazyReader passes the ASCII bytes of the underlying reader through.
+// At the first non-ASCII byte, it detects the encoding of a window
+// starting at this byte and switches to the corresponding decoder.
+type lazyReader struct {
+	r        io.Reader     // the underlying reader
+	params   *readerParams // the detection parameters, like the fallback
+	window   int           // the size of the detection window
+	context  []byte        // the last ASCII bytes read, used as detection context
+	offset   int64         // the number of bytes read so far
+	switchAt int64         // the offset of the first non-ASCII byte, -1 if not seen yet
+	enc      string        // the encoding detected at the switch
+	dr       io.Reader     // the reader used after the switch
+	trace    tracer        // the receiver of the detection events, nil if none
+	lim      *limiter      // the counter of the consumed bytes, nil if none
+}
+
+// newLazyReader returns a new lazyReader that reads from r and detects
+// the encoding with the params (see detect) on windows of the peek size.
+// The detection steps are reported to the tracer of params, if not nil,
+// and the input bytes consumed are counted by lim, if not nil.
+func newLazyReader(r io.Reader, params *readerParams, lim *limiter) *lazyReader {
+	return &lazyReader{
+		r:        r,
+		params:   params,
+		window:   params.peekSize,
+		switchAt: -1,
+		trace:    params.trace,
+		lim:      lim,
+	}
+}
+
+// passed counts the n bytes passed through as consumed.
+func (l *lazyReader) passed(n int) {
+	if l.lim != nil {
+		l.lim.consumed += int64(n)
+	}
+}
+
+// Read implements the io.Reader interface.
+func (l *lazyReader) Read(p []byte) (n int, err error) {
+	if l.dr != nil {
+		n, err = l.dr.Read(p)
+		if l.enc == "UTF-8" || l.enc == "" {
+			l.passed(n)
+		}
+		return n, err
+	}
+	n, err = l.r.Read(p)
+	i := bytes.IndexFunc(p[:n], func(r rune) bool { return r >= utf8.RuneSelf })
+	if i < 0 {
+		l.offset += int64(n)
+		l.keepContext(p[:n])
+		l.passed(n)
+		return n, err
+	}
+	// the first non-ASCII byte is at l.offset + i
+	if err != nil && err != io.EOF {
+		// the error is returned after the bytes already read
+		l.r = errReader{err}
+	}
+	rest := bytes.Clone(p[i:n])
+	l.keepContext(p[:i])
+	l.switchAt = l.offset + int64(i)
+	l.offset += int64(i)
+	l.switchDecoder(rest, err != nil)
+	if i > 0 {
+		l.passed(i)
+		return i, nil
+	}
+	return l.Read(p)
+}
+
+// keepContext keeps the last ASCII bytes of b as context for the detection.
+func (l *lazyReader) keepContext(b []byte) {
+	if len(b) >= lazyContext {
+		l.context = append(l.context[:0], b[len(b)-lazyContext:]...)
+		return
+	}
+	l.context = append(l.context, b...)
+	if len(l.context) > lazyContext {
+		l.context = l.context[len(l.context)-lazyContext:]
+	}
+}
+
+// switchDecoder reads the detection window, that starts with rest,
+// detects its encoding and sets the reader used for the rest of the input.
+// If the input is done (eof is true), the window is rest.
+// A read error is returned by the new reader, after the window.
+// At the end of the input, the last rune of the window is not truncated
+// and the fallback applies (see WithFallback).
+func (l *lazyReader) switchDecoder(rest []byte, eof bool) {
+	window := rest
+	if !eof && len(rest) < l.window {
+		window = make([]byte, l.window)
+		copy(window, rest)
+		n, err := io.ReadFull(l.r, window[len(rest):])
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				l.r = errReader{err}
+			}
+			eof = true
+		}
+		window = window[:len(rest)+n]
+	}
+	l.context = append(l.context, window...)
+	l.enc = detect(l.context, l.context, eof, l.params).Encoding
+	l.context = nil
+	defer func() {
+		l.trace.emit("lazy", "decoder switched at the first non-ASCII byte",
+			slog.Int64("offset", l.switchAt), slog.String("encoding", l.enc))
+	}()
+	src := io.MultiReader(bytes.NewReader(window), l.r)
+	l.dr = src
+	if l.enc == "UTF-8" {
+		return
+	}
+	// only the ASCII compatible encodings make sense after ASCII text,
+	// the input is left unchanged for the other ones, like for an unknown encoding
+	if e := lookup(l.enc); e != nil && !strings.HasPrefix(l.enc, "UTF-") {
+		l.dr = transform.NewReader(src, l.lim.consume(e.NewDecoder()))
+		return
+	}
+	l.enc = ""
+}
+
+// errReader is an io.Reader that always returns err.
+type errReader struct {
+	err error
+}
+
+// Read implements the io.Reader interface.
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,108 @@
+package utf8reader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNew_lazyDetection(t *testing.T) {
+	// 100000 bytes of ASCII csv followed by a single windows-1252 "é"
+	header := bytes.Repeat([]byte("id;name;price\n1;cafe;2.5\n"), 4000)
+	in := append(bytes.Clone(header), "2;caf\xe9;3.0\n3;the;1.5\n"...)
+	want := string(header) + "2;café;3.0\n3;the;1.5\n"
+
+	for _, oneByte := range []bool{false, true} {
+		var src io.Reader = bytes.NewReader(in)
+		if oneByte {
+			src = iotest.OneByteReader(src)
+		}
+		r, err := NewReader(src, WithLazyDetection())
+		if err != nil {
+			t.Fatalf("NewReader(..., WithLazyDetection()) error = %v, want nil", err)
+		}
+		if r.Encoding() != "UTF-8" || r.SwitchOffset() != -1 {
+			t.Errorf("before reading: Encoding(), SwitchOffset() = %s, %d, want UTF-8, -1", r.Encoding(), r.SwitchOffset())
+		}
+		b, err := io.ReadAll(r)
+		if err != nil || string(b) != want {
+			t.Errorf("io.ReadAll(r) = ...%q, %v, want ...%q, nil", b[max(0, len(b)-30):], err, want[len(want)-30:])
+		}
+		if enc := strings.ToUpper(r.Encoding()); enc != "ISO-8859-1" && enc != "WINDOWS-1252" {
+			t.Errorf("after reading: Encoding() = %s, want ISO-8859-1 or WINDOWS-1252", r.Encoding())
+		}
+		if r.SwitchOffset() != int64(len(header)+5) {
+			t.Errorf("after reading: SwitchOffset() = %d, want %d", r.SwitchOffset(), len(header)+5)
+		}
+	}
+
+	// late UTF-8 stays UTF-8
+	in = append(bytes.Clone(header), "2;café;3.0\n"...)
+	r := New(bytes.NewReader(in), WithLazyDetection())
+	b, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(b, in) || r.Encoding() != "UTF-8" || r.SwitchOffset() != int64(len(header)+5) {
+		t.Errorf("late UTF-8: %v, %s, %d, want the input, UTF-8, %d", err, r.Encoding(), r.SwitchOffset(), len(header)+5)
+	}
+
+	// the non-ASCII byte is the last one, detected or replaced by the fallback
+	in = append(bytes.Clone(header[:13000]), "42,Caf\xe9"...)
+	for _, d := range []struct {
+		options []Option
+		enc     string
+	}{
+		{[]Option{WithLazyDetection()}, "ISO-8859-1"},
+		{[]Option{WithLazyDetection(), WithFallback("windows-1252"), WithMinConfidence(100)}, "windows-1252"},
+	} {
+		r := New(iotest.HalfReader(bytes.NewReader(in)), d.options...)
+		b, err := io.ReadAll(r)
+		if err != nil || !strings.HasSuffix(string(b), "\n42,Café") || r.Encoding() != d.enc || r.SwitchOffset() != 13006 {
+			t.Errorf("last byte: ...%q, %v, %s, %d, want ...42,Café, nil, %s, 13006", b[len(b)-10:], err, r.Encoding(), r.SwitchOffset(), d.enc)
+		}
+	}
+
+	// the lazy mode is not used when the peek is not ASCII
+	r = New(strings.NewReader("café"), WithLazyDetection())
+	if r.lazy != nil || r.SwitchOffset() != -1 {
+		t.Errorf("New(\"café\", WithLazyDetection()).lazy = %v, want nil", r.lazy)
+	}
+}
+
+// dataErrReader returns all its data with err in a single Read.
+type dataErrReader struct {
+	data []byte
+	err  error
+}
+
+func (r *dataErrReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	if len(r.data) > 0 {
+		return n, nil
+	}
+	return n, r.err
+}
+
+func TestLazyReader_error(t *testing.T) {
+	errRead := errors.New("read error")
+	for _, half := range []bool{false, true} {
+		// the ASCII bytes and the window are returned before the error
+		l := newLazyReader(&dataErrReader{data: []byte("abc;caf\xc3\xa9"), err: errRead}, newParams(), nil)
+		var src io.Reader = l
+		if half {
+			src = iotest.HalfReader(l)
+		}
+		b, err := io.ReadAll(src)
+		if err != errRead || string(b) != "abc;café" {
+			t.Errorf("io.ReadAll(lazy) = %q, %v, want %q, %v", b, err, "abc;café", errRead)
+		}
+		if l.enc != "UTF-8" || l.switchAt != 7 {
+			t.Errorf("lazy encoding, offset = %s, %d, want UTF-8, 7", l.enc, l.switchAt)
+		}
+	}
+}
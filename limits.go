@@ -0,0 +1,184 @@
+package utf8reader
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// Limit is a limit set by WithMaxInputBytes, WithMaxOutputBytes or WithMaxExpansionRatio.
+type Limit int
+
+const (
+	// LimitInput is the maximal number of bytes read from the input.
+	LimitInput Limit = iota
+	// LimitOutput is the maximal number of UTF-8 bytes produced.
+	LimitOutput
+	// LimitExpansion is the maximal ratio between the output and the input sizes.
+	LimitExpansion
+)
+
+// limitNames are the names of the limits.
+var limitNames = []string{"input", "output", "expansion ratio"}
+
+// String returns the name of the limit.
+func (l Limit) String() string {
+	if l < 0 || int(l) >= len(limitNames) {
+		return "Limit(?)"
+	}
+	return limitNames[l]
+}
+
+// LimitError is the error returned when a limit is exceeded.
+// The bytes produced before the limit is reached are returned, but no more.
+type LimitError struct {
+	Limit Limit   // the exceeded limit
+	Max   float64 // the value of the limit, in bytes or the expansion ratio
+	In    int64   // the number of bytes read from the input so far
+	Out   int64   // the number of bytes produced so far, including the rejected ones
+}
+
+// Error returns the description of the error.
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitInput:
+		return fmt.Sprintf("utf8reader: input exceeds %.0f bytes", e.Max)
+	case LimitOutput:
+		return fmt.Sprintf("utf8reader: output exceeds %.0f bytes", e.Max)
+	default:
+		return fmt.Sprintf("utf8reader: output of %d bytes exceeds %g times the input of %d bytes", e.Out, e.Max, e.In)
+	}
+}
+
+// limiter counts the input and output bytes of a Reader and checks the limits.
+// The expansion ratio is checked against the input bytes consumed by the decoder,
+// not against the input bytes read ahead (like the peeked ones).
+type limiter struct {
+	maxIn    int64   // the maximal number of input bytes, 0 if unlimited
+	maxOut   int64   // the maximal number of output bytes, 0 if unlimited
+	ratio    float64 // the maximal expansion ratio, 0 if unlimited
+	in       int64   // the number of input bytes read so far
+	consumed int64   // the number of input bytes consumed by the decoder so far
+	out      int64   // the number of output bytes produced so far, including the rejected ones
+	stats    counts  // the statistics of the returned bytes
+}
+
+// sampled counts n input bytes read to sample the input (see WithSampling),
+// and returns a *LimitError if the input limit is exceeded.
+func (l *limiter) sampled(n int) error {
+	l.in += int64(n)
+	if l.maxIn > 0 && l.in > l.maxIn {
+		return &LimitError{Limit: LimitInput, Max: float64(l.maxIn), In: l.in, Out: l.out}
+	}
+	return nil
+}
+
+// consume returns t, that counts the input bytes it consumes.
+// If t is nil, the output bytes are counted as consumed input bytes.
+func (l *limiter) consume(t transform.Transformer) transform.Transformer {
+	if t == nil || l == nil {
+		return t
+	}
+	return &consumer{Transformer: t, l: l}
+}
+
+// consumer is a transformer that counts the input bytes consumed by the underlying one.
+type consumer struct {
+	transform.Transformer
+	l *limiter
+}
+
+// Transform implements the transform.Transformer interface.
+func (c *consumer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	nDst, nSrc, err = c.Transformer.Transform(dst, src, atEOF)
+	c.l.consumed += int64(nSrc)
+	return nDst, nSrc, err
+}
+
+// input returns a reader that counts the bytes read from r
+// and fails once more than maxIn bytes are read.
+func (l *limiter) input(r io.Reader) io.Reader {
+	return &limitedInput{r: r, l: l}
+}
+
+// output returns a reader that counts the bytes read from r
+// and fails once more than maxOut bytes, or more than ratio times
+// the consumed input bytes, are read. If passthrough is true,
+// the bytes of r are the input bytes, consumed as they are read.
+func (l *limiter) output(r io.Reader, passthrough bool) io.Reader {
+	return &limitedOutput{r: r, l: l, passthrough: passthrough}
+}
+
+// limitedInput is the input side of a limiter.
+type limitedInput struct {
+	r   io.Reader
+	l   *limiter
+	err error // the sticky limit error
+}
+
+// Read reads from the underlying reader, at most one byte past the limit.
+func (r *limitedInput) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	l := r.l
+	if l.maxIn > 0 && int64(len(p)) > l.maxIn-l.in+1 {
+		p = p[:l.maxIn-l.in+1]
+	}
+	n, err := r.r.Read(p)
+	l.in += int64(n)
+	if l.maxIn > 0 && l.in > l.maxIn {
+		n -= int(l.in - l.maxIn)
+		r.err = &LimitError{Limit: LimitInput, Max: float64(l.maxIn), In: l.in, Out: l.out}
+		return n, r.err
+	}
+	return n, err
+}
+
+// limitedOutput is the output side of a limiter.
+type limitedOutput struct {
+	r           io.Reader
+	l           *limiter
+	passthrough bool  // the bytes of r are the input bytes
+	err         error // the sticky limit error
+}
+
+// Read reads from the underlying reader and checks the output limits.
+// If a limit is exceeded, the output is cut at the last rune boundary before the limit.
+// The bytes returned are counted in l.stats.
+func (r *limitedOutput) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	l := r.l
+	if l.maxOut > 0 && int64(len(p)) > l.maxOut-l.out+1 {
+		p = p[:l.maxOut-l.out+1]
+	}
+	n, err := r.r.Read(p)
+	if r.passthrough {
+		l.consumed += int64(n)
+	}
+	before := l.out
+	l.out += int64(n)
+	if l.maxOut > 0 && l.out > l.maxOut {
+		r.err = &LimitError{Limit: LimitOutput, Max: float64(l.maxOut), In: l.in, Out: l.out}
+		n, err = runeCut(p[:n], int(l.maxOut-before)), r.err
+	} else if l.ratio > 0 && float64(l.out) > l.ratio*float64(l.consumed) {
+		r.err = &LimitError{Limit: LimitExpansion, Max: l.ratio, In: l.in, Out: l.out}
+		n, err = runeCut(p[:n], int(l.ratio*float64(l.consumed))-int(before)), r.err
+	}
+	l.stats.count(p[:n])
+	return n, err
+}
+
+// runeCut returns the length of the longest prefix of p, of at most n bytes,
+// that does not end with a truncated rune.
+func runeCut(p []byte, n int) int {
+	n = max(0, min(n, len(p)))
+	for n > 0 && n < len(p) && !utf8.RuneStart(p[n]) {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package utf8reader
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLimits(t *testing.T) {
+	const text = "Това е текст на български език.\n"
+	in, _ := lookup("windows-1251").NewEncoder().String(strings.Repeat(text, 300))
+	out := strings.Repeat(text, 300)
+
+	tests := []struct {
+		name   string
+		option Option
+		limit  Limit
+		read   int // the number of bytes read before the error, -1 if no error, 0 if not checked
+	}{
+		{"no limit", nil, 0, -1},
+		{"input ok", WithMaxInputBytes(int64(len(in))), 0, -1},
+		{"output ok", WithMaxOutputBytes(int64(len(out))), 0, -1},
+		{"ratio ok", WithMaxExpansionRatio(2), 0, -1},
+		{"input", WithMaxInputBytes(5000), LimitInput, 0},
+		// the output is cut at the last rune boundary
+		{"output", WithMaxOutputBytes(5000), LimitOutput, 4999},
+		{"ratio", WithMaxExpansionRatio(1.5), LimitExpansion, 0},
+	}
+	for _, tt := range tests {
+		var options []Option
+		if tt.option != nil {
+			options = append(options, tt.option)
+		}
+		r, err := NewReader(strings.NewReader(in), options...)
+		if err != nil {
+			t.Fatalf("%s: NewReader() error = %v, want nil", tt.name, err)
+		}
+		b, err := io.ReadAll(r)
+		if tt.read == -1 {
+			if err != nil || string(b) != out {
+				t.Errorf("%s: io.ReadAll(r) = %d bytes, %v, want %d bytes, nil", tt.name, len(b), err, len(out))
+			}
+			continue
+		}
+		var le *LimitError
+		if !errors.As(err, &le) || le.Limit != tt.limit {
+			t.Errorf("%s: io.ReadAll(r) error = %v, want a %s *LimitError", tt.name, err, tt.limit)
+			continue
+		}
+		if tt.read > 0 && len(b) != tt.read {
+			t.Errorf("%s: io.ReadAll(r) = %d bytes, want %d", tt.name, len(b), tt.read)
+		}
+		if !strings.HasPrefix(out, string(b)) || !utf8.Valid(b) {
+			t.Errorf("%s: io.ReadAll(r) is not a prefix of the output", tt.name)
+		}
+		// the error is sticky
+		if _, err := r.Read(make([]byte, 10)); err != le {
+			t.Errorf("%s: r.Read() error = %v, want %v", tt.name, err, le)
+		}
+	}
+
+	// the limit is exceeded in the peek
+	_, err := NewReader(strings.NewReader(in), WithMaxInputBytes(1000))
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != LimitInput || le.Error() != "utf8reader: input exceeds 1000 bytes" {
+		t.Errorf("NewReader(..., WithMaxInputBytes(1000)) error = %v, want an input *LimitError", err)
+	}
+
+	// the sampled windows are read from the input
+	in, _ = lookup("windows-1251").NewEncoder().String(strings.Repeat(text, 3000))
+	_, err = NewReader(strings.NewReader(in), WithSampling(4), WithMaxInputBytes(10000))
+	if !errors.As(err, &le) || le.Limit != LimitInput {
+		t.Errorf("NewReader(..., WithSampling(4), WithMaxInputBytes(10000)) error = %v, want an input *LimitError", err)
+	}
+}
+
+func TestLimits_options(t *testing.T) {
+	for _, opt := range []Option{WithMaxInputBytes(0), WithMaxOutputBytes(-1), WithMaxExpansionRatio(0.5)} {
+		if _, err := NewReader(strings.NewReader("test"), opt); err == nil {
+			t.Errorf("NewReader(..., invalid limit) error = nil, want non nil")
+		}
+	}
+	o := Options{MaxInputBytes: 10, MaxOutputBytes: 20, MaxExpansionRatio: 3}
+	if p := newParams(WithOptions(o)); p.err != nil || p.maxIn != 10 || p.maxOut != 20 || p.maxRatio != 3 {
+		t.Errorf("newParams(WithOptions(%+v)) = %+v", o, p)
+	}
+}
@@ -0,0 +1,146 @@
+package utf8reader
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// LineEnding is a line ending convention.
+type LineEnding int
+
+const (
+	// LF is the Unix line ending "\n".
+	LF LineEnding = iota
+	// CRLF is the Windows line ending "\r\n".
+	CRLF
+	// Native is CRLF on Windows and LF elsewhere.
+	Native
+)
+
+// String returns the name of the line ending.
+func (le LineEnding) String() string {
+	switch le {
+	case LF:
+		return "LF"
+	case CRLF:
+		return "CRLF"
+	case Native:
+		return "Native"
+	}
+	return "LineEnding(?)"
+}
+
+// ParseLineEnding returns the line ending named s ("LF", "CRLF" or "Native"),
+// the case is ignored.
+func ParseLineEnding(s string) (LineEnding, error) {
+	for _, le := range []LineEnding{LF, CRLF, Native} {
+		if strings.EqualFold(s, le.String()) {
+			return le, nil
+		}
+	}
+	return 0, fmt.Errorf("utf8reader: unknown line ending %q", s)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (le LineEnding) MarshalText() ([]byte, error) {
+	return []byte(le.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (le *LineEnding) UnmarshalText(text []byte) error {
+	l, err := ParseLineEnding(string(text))
+	if err != nil {
+		return err
+	}
+	*le = l
+	return nil
+}
+
+// bytes returns the bytes of the line ending.
+func (le LineEnding) bytes() []byte {
+	if le == CRLF || (le == Native && runtime.GOOS == "windows") {
+		return []byte("\r\n")
+	}
+	return []byte("\n")
+}
+
+// lineEndings is a transformer that replaces all the line endings by nl.
+type lineEndings struct {
+	transform.NopResetter
+	nl []byte
+}
+
+// NewLineEndingTransformer returns a transformer that converts all the line endings
+// (CRLF, CR, LF, NEL, LINE SEPARATOR and PARAGRAPH SEPARATOR) to le.
+// The input is expected to be UTF-8 encoded.
+func NewLineEndingTransformer(le LineEnding) transform.Transformer {
+	return lineEndings{nl: le.bytes()}
+}
+
+// Transform implements the transform.Transformer interface.
+func (t lineEndings) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		// copy the bytes that can not start a line ending
+		start := nSrc
+		for nSrc < len(src) && !mayStartLineEnding(src[nSrc]) {
+			nSrc++
+		}
+		n := copy(dst[nDst:], src[start:nSrc])
+		nDst += n
+		if start+n < nSrc {
+			return nDst, start + n, transform.ErrShortDst
+		}
+		if nSrc == len(src) {
+			break
+		}
+		// check for a line ending
+		size := 0
+		switch c := src[nSrc]; {
+		case c == '\n':
+			size = 1
+		case c == '\r':
+			if nSrc+1 == len(src) && !atEOF {
+				// we need the next byte to know if it is CRLF
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			size = 1
+			if nSrc+1 < len(src) && src[nSrc+1] == '\n' {
+				size = 2
+			}
+		default:
+			if !utf8.FullRune(src[nSrc:]) && !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			r, n := utf8.DecodeRune(src[nSrc:])
+			if r == '\u0085' || r == '\u2028' || r == '\u2029' {
+				size = n
+			}
+		}
+		if size == 0 {
+			// not a line ending
+			if nDst == len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = src[nSrc]
+			nDst++
+			nSrc++
+			continue
+		}
+		if nDst+len(t.nl) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], t.nl)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// mayStartLineEnding returns true if c is the first byte of a line ending.
+// 0xC2 starts NEL (U+0085), 0xE2 starts U+2028 and U+2029.
+func mayStartLineEnding(c byte) bool {
+	return c == '\n' || c == '\r' || c == 0xC2 || c == 0xE2
+}
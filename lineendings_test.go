@@ -0,0 +1,51 @@
+package utf8reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/transform"
+)
+
+func TestLineEndings(t *testing.T) {
+	data := []struct {
+		le  LineEnding
+		in  string
+		out string
+	}{
+		{LF, "a\r\nb\rc\nd", "a\nb\nc\nd"},
+		{LF, "a\r\r\nb\n\rc\r", "a\n\nb\n\nc\n"},
+		{LF, "a\u0085b\u2028c\u2029d", "a\nb\nc\nd"},
+		{LF, "é €", "é €"},
+		{CRLF, "a\r\nb\rc\nd", "a\r\nb\r\nc\r\nd"},
+		{CRLF, "a\u0085b\u2028c\u2029d\r\n", "a\r\nb\r\nc\r\nd\r\n"},
+		{LF, "truncated \xe2\x80", "truncated \xe2\x80"},
+	}
+	for _, d := range data {
+		tr := NewLineEndingTransformer(d.le)
+		got, _, err := transform.String(tr, d.in)
+		if err != nil || got != d.out {
+			t.Errorf("transform.String(%v, %q) = %q, %v, want %q, nil", d.le, d.in, got, err, d.out)
+		}
+		// one byte at a time to check the buffer boundaries
+		b, err := io.ReadAll(transform.NewReader(iotest.OneByteReader(strings.NewReader(d.in)), tr))
+		if err != nil || string(b) != d.out {
+			t.Errorf("one byte reader %v on %q = %q, %v, want %q, nil", d.le, d.in, b, err, d.out)
+		}
+	}
+}
+
+func TestNew_lineEndings(t *testing.T) {
+	// windows-1251 "Тест" with CRLF and CR
+	in := []byte{0xD2, 0xE5, 0xF1, 0xF2, 0x0D, 0x0A, 0xD2, 0xE5, 0xF1, 0xF2, 0x0D, 0xD2, 0xE5, 0xF1, 0xF2}
+	r := New(strings.NewReader(string(in)), WithLineEndings(LF))
+	if r == nil {
+		t.Fatalf("New(..., WithLineEndings(LF)) = nil, want *Reader")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "Тест\nТест\nТест" {
+		t.Errorf("io.ReadAll(r) = %q, %v, want \"Тест\\nТест\\nТест\", nil", b, err)
+	}
+}
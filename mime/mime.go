@@ -0,0 +1,257 @@
+// Package mime decodes the MIME messages (like emails) to UTF-8.
+//
+// A Reader walks the parts of a message, decodes their transfer encoding
+// (quoted-printable or base64) and converts the text parts to UTF-8 with utf8reader,
+// using the declared charset as a hint (see utf8reader.WithHint), so the parts
+// declaring a wrong charset are still decoded correctly.
+// The encoded-words (RFC 2047) of the headers are decoded with the same logic (see DecodeHeader).
+package mime
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io"
+	stdmime "mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"github.com/kpym/utf8reader"
+)
+
+// Part is a leaf part of a message, not a multipart.
+type Part struct {
+	Header    textproto.MIMEHeader // the header of the part, with the encoded-words decoded
+	MediaType string               // the lowercase media type, like "text/plain"
+	Params    map[string]string    // the parameters of the Content-Type
+	Filename  string               // the decoded file name of the attachment, if any
+	Encoding  string               // the detected encoding of a text part, "" for the other parts
+	Body      io.Reader            // the body without transfer encoding, converted to UTF-8 for the text parts
+}
+
+// Reader reads a MIME message.
+type Reader struct {
+	// Header is the header of the message, with the encoded-words decoded.
+	Header textproto.MIMEHeader
+
+	raw     textproto.MIMEHeader
+	body    io.Reader
+	options []utf8reader.Option
+}
+
+// NewReader reads the header of the message from r, and returns a Reader for its parts.
+// The options are used to convert the text parts and the encoded-words to UTF-8.
+func NewReader(r io.Reader, options ...utf8reader.Option) (*Reader, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	raw := textproto.MIMEHeader(msg.Header)
+	return &Reader{
+		Header:  decodeHeaders(raw, options),
+		raw:     raw,
+		body:    msg.Body,
+		options: options,
+	}, nil
+}
+
+// Walk calls fn for each leaf part of the message, in order, including the parts
+// of the nested multiparts and of the attached messages (message/rfc822).
+// A message that is not a multipart is a single part.
+// The body of a part can only be read in fn.
+// Walk stops at the first error returned by fn, and returns it.
+func (r *Reader) Walk(fn func(*Part) error) error {
+	return r.walk(r.raw, r.body, fn)
+}
+
+// walk walks the part with the header h and the (transfer encoded) body.
+func (r *Reader) walk(h textproto.MIMEHeader, body io.Reader, fn func(*Part) error) error {
+	mediatype, params, err := stdmime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		// the default content type (RFC 2045)
+		mediatype, params = "text/plain", map[string]string{"charset": "us-ascii"}
+	}
+	body = transferDecoder(h.Get("Content-Transfer-Encoding"), body)
+
+	switch {
+	case strings.HasPrefix(mediatype, "multipart/"):
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := r.walk(p.Header, p, fn); err != nil {
+				return err
+			}
+		}
+	case mediatype == "message/rfc822":
+		msg, err := mail.ReadMessage(bufio.NewReader(body))
+		if err != nil {
+			return err
+		}
+		return r.walk(textproto.MIMEHeader(msg.Header), msg.Body, fn)
+	}
+
+	part := &Part{
+		Header:    decodeHeaders(h, r.options),
+		MediaType: mediatype,
+		Params:    params,
+		Body:      body,
+	}
+	if _, dparams, err := stdmime.ParseMediaType(h.Get("Content-Disposition")); err == nil && dparams["filename"] != "" {
+		part.Filename = DecodeHeader(dparams["filename"], r.options...)
+	} else if params["name"] != "" {
+		part.Filename = DecodeHeader(params["name"], r.options...)
+	}
+	if strings.HasPrefix(mediatype, "text/") {
+		ur, err := utf8reader.NewReader(body, withHint(r.options, params["charset"])...)
+		if err != nil {
+			return err
+		}
+		part.Body, part.Encoding = ur, ur.Encoding()
+	}
+	return fn(part)
+}
+
+// transferDecoder returns the reader that decodes body with the transfer encoding cte.
+func transferDecoder(cte string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(cte)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return body
+}
+
+// withHint returns the options followed by the hint of the charset, if any.
+func withHint(options []utf8reader.Option, charset string) []utf8reader.Option {
+	// the charset parameter can contain a language (RFC 2231)
+	charset, _, _ = strings.Cut(charset, "*")
+	if charset == "" {
+		return options
+	}
+	return append(options[:len(options):len(options)], utf8reader.WithHint(charset))
+}
+
+// decodeHeaders returns a copy of h with the encoded-words decoded.
+func decodeHeaders(h textproto.MIMEHeader, options []utf8reader.Option) textproto.MIMEHeader {
+	decoded := make(textproto.MIMEHeader, len(h))
+	for k, vs := range h {
+		for _, v := range vs {
+			decoded[k] = append(decoded[k], DecodeHeader(v, options...))
+		}
+	}
+	return decoded
+}
+
+// encodedWord matches an encoded-word: =?charset?encoding?text?=
+var encodedWord = regexp.MustCompile(`=\?([^?\s]+)\?([bBqQ])\?([^?\s]*)\?=`)
+
+// DecodeHeader decodes the encoded-words (RFC 2047) of a header value.
+// The declared charset of the words is a hint (see utf8reader.WithHint),
+// so a mislabeled UTF-8 word is decoded as UTF-8.
+// The adjacent words with the same charset are decoded together, even if a character
+// is split between two words. The invalid words are left unchanged.
+func DecodeHeader(s string, options ...utf8reader.Option) string {
+	matches := encodedWord.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	var raw []byte // the bytes of the current run of words
+	var run string // the text of the current run of words
+	var charset string
+	flush := func() {
+		if run == "" {
+			return
+		}
+		if text, err := decodeText(raw, charset, options); err == nil {
+			b.WriteString(text)
+		} else {
+			b.WriteString(run)
+		}
+		raw, run, charset = nil, "", ""
+	}
+	last := 0
+	for _, m := range matches {
+		gap := s[last:m[0]]
+		if run == "" || strings.TrimSpace(gap) != "" {
+			flush()
+			b.WriteString(gap)
+		}
+		word, cs := s[m[0]:m[1]], s[m[2]:m[3]]
+		data, err := decodeWord(s[m[4]:m[5]], s[m[6]:m[7]])
+		switch {
+		case err != nil:
+			flush()
+			b.WriteString(word)
+		case run != "" && strings.EqualFold(cs, charset):
+			raw, run = append(raw, data...), run+word
+		default:
+			flush()
+			raw, run, charset = data, word, cs
+		}
+		last = m[1]
+	}
+	flush()
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// errInvalidWord is the error of an invalid encoded-word.
+var errInvalidWord = errors.New("mime: invalid encoded-word")
+
+// decodeWord returns the bytes of the text of an encoded-word with the encoding "B" or "Q".
+func decodeWord(encoding, text string) ([]byte, error) {
+	if encoding == "B" || encoding == "b" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	var b []byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '_':
+			b = append(b, ' ')
+		case '=':
+			if i+2 >= len(text) {
+				return nil, errInvalidWord
+			}
+			h, l := unhex(text[i+1]), unhex(text[i+2])
+			if h < 0 || l < 0 {
+				return nil, errInvalidWord
+			}
+			b = append(b, byte(h<<4|l))
+			i += 2
+		default:
+			b = append(b, c)
+		}
+	}
+	return b, nil
+}
+
+// unhex returns the value of the hexadecimal digit c, or -1.
+func unhex(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	case 'A' <= c && c <= 'F':
+		return int(c - 'A' + 10)
+	}
+	return -1
+}
+
+// decodeText converts data declared in charset to UTF-8.
+func decodeText(data []byte, charset string, options []utf8reader.Option) (string, error) {
+	text, _, err := utf8reader.DecodeBytes(data, withHint(options, charset)...)
+	return string(text), err
+}
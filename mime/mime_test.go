@@ -0,0 +1,109 @@
+package mime
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{"=?iso-8859-1?q?caf=E9?=", "café"},
+		{"=?ISO-8859-1?Q?caf=C3=A9_cr=C3=A8me?=", "café crème"},
+		{"=?utf-8?b?w6dh?= va", "ça va"},
+		{"=?utf-8?q?caf=C3?= =?utf-8?q?=A9?=", "café"},
+		{"Re: =?koi8-r?b?8NLJ18XU?= =?utf-8?q?!?=", "Re: Привет!"},
+		{"=?utf-8?q?a?=  b =?utf-8?q?c?=", "a  b c"},
+		{"=?utf-8?b?invalid!?=", "=?utf-8?b?invalid!?="},
+		{"=?unknown?q?caf=C3=A9?=", "café"},
+	}
+	for _, tt := range tests {
+		if got := DecodeHeader(tt.in); got != tt.want {
+			t.Errorf("DecodeHeader(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+const message = "From: =?iso-8859-1?q?Fran=E7ois?= <f@example.com>\r\n" +
+	"Subject: =?iso-8859-1?q?r=C3=A9sum=C3=A9?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Un caf=E9 cr=E8me, s'il vous pla=EEt.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+VW4gY2Fmw6kgY3LDqG1lPC9wPg==\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/octet-stream\r\n" +
+	"Content-Disposition: attachment; filename=\"=?utf-8?q?r=C3=A9sum=C3=A9.bin?=\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"6QAB\r\n" +
+	"--outer--\r\n"
+
+func TestReader(t *testing.T) {
+	r, err := NewReader(strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v, want nil", err)
+	}
+	if got := r.Header.Get("Subject"); got != "résumé" {
+		t.Errorf("Subject = %q, want \"résumé\"", got)
+	}
+	if got := r.Header.Get("From"); got != "François <f@example.com>" {
+		t.Errorf("From = %q, want \"François <f@example.com>\"", got)
+	}
+
+	want := []struct {
+		mediatype, filename, encoding, body string
+	}{
+		{"text/plain", "", "iso-8859-1", "Un café crème, s'il vous plaît."},
+		{"text/html", "", "UTF-8", "<p>Un café crème</p>"},
+		{"application/octet-stream", "résumé.bin", "", "\xe9\x00\x01"},
+	}
+	i := 0
+	err = r.Walk(func(p *Part) error {
+		if i >= len(want) {
+			t.Errorf("unexpected part %d: %s", i, p.MediaType)
+			return nil
+		}
+		body, err := io.ReadAll(p.Body)
+		if err != nil {
+			return err
+		}
+		w := want[i]
+		if p.MediaType != w.mediatype || p.Filename != w.filename || p.Encoding != w.encoding || string(body) != w.body {
+			t.Errorf("part %d = %s, %q, %s, %q, want %s, %q, %s, %q", i, p.MediaType, p.Filename, p.Encoding, body, w.mediatype, w.filename, w.encoding, w.body)
+		}
+		i++
+		return nil
+	})
+	if err != nil || i != len(want) {
+		t.Errorf("r.Walk() = %v after %d parts, want nil after %d parts", err, i, len(want))
+	}
+
+	// a single part message, and the error of fn
+	r, _ = NewReader(strings.NewReader("Subject: test\r\n\r\ncaf\xe9\r\n"))
+	err = r.Walk(func(p *Part) error {
+		b, _ := io.ReadAll(p.Body)
+		if p.MediaType != "text/plain" || string(b) != "café\r\n" {
+			t.Errorf("single part = %s, %q, want text/plain, \"café\\r\\n\"", p.MediaType, b)
+		}
+		return io.ErrShortWrite
+	})
+	if err != io.ErrShortWrite {
+		t.Errorf("r.Walk() = %v, want io.ErrShortWrite", err)
+	}
+}
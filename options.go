@@ -0,0 +1,145 @@
+package utf8reader
+
+import (
+	"flag"
+)
+
+// Options is the configuration of a Reader, in a form that can be loaded
+// from a configuration file (JSON or YAML) or set with command line flags
+// (see RegisterFlags). The zero value is the default configuration.
+// Use WithOptions to convert it to an Option, and Validate to check it.
+type Options struct {
+	// PeekSize is the number of bytes used to detect the encoding,
+	// 0 for the default size (see WithPeekSize).
+	PeekSize int `json:"peek_size,omitempty" yaml:"peek_size,omitempty"`
+	// MaxPeekSize is the maximal peek size in the adaptive mode,
+	// 0 to disable the adaptive mode (see WithAdaptivePeek).
+	MaxPeekSize int `json:"max_peek_size,omitempty" yaml:"max_peek_size,omitempty"`
+	// MinConfidence is the confidence that stops the adaptive peek,
+	// 0 for the default (see WithMinConfidence).
+	MinConfidence int `json:"min_confidence,omitempty" yaml:"min_confidence,omitempty"`
+	// Samples is the number of windows sampled from the seekable inputs,
+	// 0 for no sampling (see WithSampling).
+	Samples int `json:"samples,omitempty" yaml:"samples,omitempty"`
+	// RandomSampling sets random offsets for the sampled windows (see WithRandomSampling).
+	RandomSampling bool `json:"random_sampling,omitempty" yaml:"random_sampling,omitempty"`
+	// Hint is the declared charset of the input, "" if none (see WithHint).
+	Hint string `json:"hint,omitempty" yaml:"hint,omitempty"`
+	// Fallback is the charset used if the detection is not conclusive,
+	// "" if none (see WithFallback).
+	Fallback string `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	// LazyDetection enables the lazy detection mode (see WithLazyDetection).
+	LazyDetection bool `json:"lazy_detection,omitempty" yaml:"lazy_detection,omitempty"`
+	// Normalization is a comma separated list of transforms, like "NFKC,fold"
+	// (see ParseTransform), "" for no normalization.
+	Normalization string `json:"normalization,omitempty" yaml:"normalization,omitempty"`
+	// Sanitize is the sanitization policy, nil for no sanitization.
+	Sanitize *SanitizePolicy `json:"sanitize,omitempty" yaml:"sanitize,omitempty"`
+	// Transliteration is the transliteration language, like "bg" or "generic"
+	// (see NewTransliterator), "" for no transliteration.
+	Transliteration string `json:"transliteration,omitempty" yaml:"transliteration,omitempty"`
+	// LineEndings is "LF", "CRLF" or "Native", "" for no conversion.
+	LineEndings string `json:"line_endings,omitempty" yaml:"line_endings,omitempty"`
+	// MaxInputBytes is the maximal number of bytes read from the input,
+	// 0 for no limit (see WithMaxInputBytes).
+	MaxInputBytes int64 `json:"max_input_bytes,omitempty" yaml:"max_input_bytes,omitempty"`
+	// MaxOutputBytes is the maximal number of bytes produced,
+	// 0 for no limit (see WithMaxOutputBytes).
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+	// MaxExpansionRatio is the maximal ratio between the output and the input sizes,
+	// 0 for no limit (see WithMaxExpansionRatio).
+	MaxExpansionRatio float64 `json:"max_expansion_ratio,omitempty" yaml:"max_expansion_ratio,omitempty"`
+}
+
+// WithOptions sets all the options from o.
+// The transforms are applied in this order: normalization, sanitization,
+// transliteration and line endings conversion.
+func WithOptions(o Options) Option {
+	return func(p *readerParams) {
+		if o.PeekSize != 0 {
+			WithPeekSize(o.PeekSize)(p)
+		}
+		if o.MaxPeekSize != 0 {
+			WithAdaptivePeek(o.MaxPeekSize)(p)
+		}
+		if o.MinConfidence != 0 {
+			WithMinConfidence(o.MinConfidence)(p)
+		}
+		if o.Samples != 0 {
+			if o.RandomSampling {
+				WithRandomSampling(o.Samples)(p)
+			} else {
+				WithSampling(o.Samples)(p)
+			}
+		}
+		if o.Hint != "" {
+			WithHint(o.Hint)(p)
+		}
+		if o.Fallback != "" {
+			WithFallback(o.Fallback)(p)
+		}
+		if o.LazyDetection {
+			WithLazyDetection()(p)
+		}
+		if o.Normalization != "" {
+			WithNormalization(o.Normalization)(p)
+		}
+		if o.Sanitize != nil {
+			WithSanitize(*o.Sanitize)(p)
+		}
+		if o.Transliteration != "" {
+			WithTransliteration(o.Transliteration)(p)
+		}
+		if o.LineEndings != "" {
+			le, err := ParseLineEnding(o.LineEndings)
+			if err != nil {
+				p.addErr(err)
+			} else {
+				WithLineEndings(le)(p)
+			}
+		}
+		if o.MaxInputBytes != 0 {
+			WithMaxInputBytes(o.MaxInputBytes)(p)
+		}
+		if o.MaxOutputBytes != 0 {
+			WithMaxOutputBytes(o.MaxOutputBytes)(p)
+		}
+		if o.MaxExpansionRatio != 0 {
+			WithMaxExpansionRatio(o.MaxExpansionRatio)(p)
+		}
+	}
+}
+
+// Validate returns an error reporting all the invalid options, or nil.
+func (o Options) Validate() error {
+	return newParams(WithOptions(o)).err
+}
+
+// RegisterFlags defines the flags -peek-size, -max-peek-size, -min-confidence,
+// -samples, -random-samples, -hint, -fallback, -lazy, -normalization, -sanitize, -transliteration,
+// -line-endings, -max-input, -max-output and -max-expansion in fs, that set the options.
+// The -sanitize flag sets the same action for all categories.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.PeekSize, "peek-size", o.PeekSize, "number of bytes used to detect the encoding (0 for the default)")
+	fs.IntVar(&o.MaxPeekSize, "max-peek-size", o.MaxPeekSize, "maximal number of bytes used to detect the encoding in adaptive mode (0 to disable)")
+	fs.IntVar(&o.MinConfidence, "min-confidence", o.MinConfidence, "confidence (1-100) that stops the adaptive detection (0 for the default)")
+	fs.IntVar(&o.Samples, "samples", o.Samples, "number of windows sampled from seekable inputs to detect the encoding (0 for no sampling)")
+	fs.BoolVar(&o.RandomSampling, "random-samples", o.RandomSampling, "sample the windows at random offsets")
+	fs.StringVar(&o.Hint, "hint", o.Hint, "declared charset of the input, used unless a BOM is present or the input is obviously UTF-8")
+	fs.StringVar(&o.Fallback, "fallback", o.Fallback, "charset used if the detection is not conclusive")
+	fs.BoolVar(&o.LazyDetection, "lazy", o.LazyDetection, "defer the detection to the first non-ASCII byte")
+	fs.StringVar(&o.Normalization, "normalization", o.Normalization, "comma separated list of transforms, like NFC or NFKC,fold")
+	fs.Func("sanitize", "action (keep, strip, replace or escape) applied to the control, invisible and bidi characters", func(s string) error {
+		a, err := ParseSanitizeAction(s)
+		if err != nil {
+			return err
+		}
+		o.Sanitize = &SanitizePolicy{Control: a, Invisible: a, Bidi: a}
+		return nil
+	})
+	fs.StringVar(&o.Transliteration, "transliteration", o.Transliteration, "transliterate to ASCII using the language rules (bg, ru, uk, de or generic)")
+	fs.StringVar(&o.LineEndings, "line-endings", o.LineEndings, "convert the line endings to LF, CRLF or Native")
+	fs.Int64Var(&o.MaxInputBytes, "max-input", o.MaxInputBytes, "maximal number of bytes read from the input (0 for no limit)")
+	fs.Int64Var(&o.MaxOutputBytes, "max-output", o.MaxOutputBytes, "maximal number of bytes produced (0 for no limit)")
+	fs.Float64Var(&o.MaxExpansionRatio, "max-expansion", o.MaxExpansionRatio, "maximal ratio between the output and the input sizes (0 for no limit)")
+}
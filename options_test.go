@@ -0,0 +1,95 @@
+package utf8reader
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestOptions_json(t *testing.T) {
+	var o Options
+	config := `{"peek_size": 8192, "normalization": "NFKC,fold", "sanitize": {"control": "strip", "bidi": "escape"}, "line_endings": "LF"}`
+	if err := json.Unmarshal([]byte(config), &o); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v, want nil", config, err)
+	}
+	if o.PeekSize != 8192 || o.Normalization != "NFKC,fold" || o.LineEndings != "LF" {
+		t.Errorf("json.Unmarshal(%s) = %+v", config, o)
+	}
+	if o.Sanitize == nil || *o.Sanitize != (SanitizePolicy{Control: SanitizeStrip, Bidi: SanitizeEscape}) {
+		t.Errorf("json.Unmarshal(%s).Sanitize = %+v", config, o.Sanitize)
+	}
+	if err := o.Validate(); err != nil {
+		t.Errorf("o.Validate() = %v, want nil", err)
+	}
+	r, err := NewReader(strings.NewReader("ＡＢ\x00\r\nc"), WithOptions(o))
+	if err != nil {
+		t.Fatalf("NewReader(..., WithOptions(o)) error = %v, want nil", err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "ab\nc" {
+		t.Errorf("io.ReadAll(r) = %q, %v, want \"ab\\nc\", nil", b, err)
+	}
+
+	config = `{"sanitize": {"control": "remove"}}`
+	if err := json.Unmarshal([]byte(config), &o); err == nil {
+		t.Errorf("json.Unmarshal(%s) error = nil, want non nil", config)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	o := Options{PeekSize: -1, Normalization: "NFX", Transliteration: "xx", LineEndings: "CR", Fallback: "latin-42"}
+	err := o.Validate()
+	if err == nil {
+		t.Fatalf("o.Validate() = nil, want non nil")
+	}
+	for _, s := range []string{"peek size", "NFX", "xx", "CR", "latin-42"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("o.Validate() = %q, want it to contain %q", err, s)
+		}
+	}
+	if _, err := NewReader(strings.NewReader("test"), WithOptions(o)); err == nil {
+		t.Errorf("NewReader(..., WithOptions(o)) error = nil, want non nil")
+	}
+	if err := (Options{}).Validate(); err != nil {
+		t.Errorf("Options{}.Validate() = %v, want nil", err)
+	}
+}
+
+func TestOptions_RegisterFlags(t *testing.T) {
+	var o Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	o.RegisterFlags(fs)
+	err := fs.Parse([]string{"-peek-size", "2048", "-hint", "cp1251", "-fallback", "koi8-r", "-normalization", "NFC", "-sanitize", "replace", "-transliteration", "bg", "-line-endings", "crlf"})
+	if err != nil {
+		t.Fatalf("fs.Parse() error = %v, want nil", err)
+	}
+	want := Options{PeekSize: 2048, Hint: "cp1251", Fallback: "koi8-r", Normalization: "NFC", Transliteration: "bg", LineEndings: "crlf"}
+	if o.Sanitize == nil || *o.Sanitize != (SanitizePolicy{SanitizeReplace, SanitizeReplace, SanitizeReplace}) {
+		t.Errorf("o.Sanitize = %+v, want all replace", o.Sanitize)
+	}
+	o.Sanitize = nil
+	if o != want {
+		t.Errorf("o = %+v, want %+v", o, want)
+	}
+	if err := fs.Parse([]string{"-sanitize", "remove"}); err == nil {
+		t.Errorf("fs.Parse(-sanitize remove) error = nil, want non nil")
+	}
+}
+
+func TestOptions_hint(t *testing.T) {
+	var o Options
+	config := `{"hint": "windows-1251", "fallback": "koi8-r"}`
+	if err := json.Unmarshal([]byte(config), &o); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v, want nil", config, err)
+	}
+	if o.Hint != "windows-1251" || o.Fallback != "koi8-r" {
+		t.Errorf("json.Unmarshal(%s) = %+v", config, o)
+	}
+	s, det, err := DecodeString("\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff", WithOptions(o))
+	if err != nil || s != "България" || det.Encoding != "windows-1251" {
+		t.Errorf("DecodeString(..., WithOptions(o)) = %q, %+v, %v, want България, windows-1251, nil", s, det, err)
+	}
+}
@@ -0,0 +1,156 @@
+package utf8reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"golang.org/x/text/transform"
+)
+
+// chunkSize is the approximate size in bytes of the chunks decoded concurrently
+// by DecodeParallel. It is a multiple of 4 to keep the UTF-16 and UTF-32 chunks aligned.
+const chunkSize = 1 << 20
+
+// chunk is a part of the source decoded by a worker.
+// The output (or the error) is sent to the result channel.
+type chunk struct {
+	start, end int64
+	result     chan chunkResult
+}
+
+// chunkResult is the output of a decoded chunk.
+type chunkResult struct {
+	out []byte
+	err error
+}
+
+// DecodeParallel decodes the size bytes of r to UTF-8 and writes the output to w.
+// The encoding is detected the same way as NewReaderAt does.
+// The source is split in chunks at safe boundaries for the detected encoding
+// (see Seeker), that are decoded concurrently by workers goroutines,
+// and the output is written in order.
+// If workers is not positive, runtime.GOMAXPROCS(0) workers are used.
+// The stateful encodings (like ISO-2022-JP) are decoded as a single chunk.
+// The transformers are created again for each chunk, so the ones shared
+// by WithTransform are not accepted, and the number of sanitized characters
+// (see WithSanitize) is not available.
+// The limits, the statistics, the hooks and the lazy detection are not supported:
+// DecodeParallel returns an error if they are set.
+// It returns the number of bytes written to w and the first error encountered.
+func DecodeParallel(w io.Writer, r io.ReaderAt, size int64, workers int, options ...Option) (int64, error) {
+	if r == nil {
+		return 0, errors.New("utf8reader: nil reader")
+	}
+	if err := parallelSupport(newParams(options...)); err != nil {
+		return 0, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	reader, err := NewReader(io.NewSectionReader(r, 0, size), options...)
+	if err != nil {
+		return 0, err
+	}
+	enc, split := reader.enc, newSplitFunc(reader.enc)
+
+	jobs := make(chan chunk)
+	order := make(chan chan chunkResult, 2*workers)
+	done := make(chan struct{})
+	defer close(done)
+
+	// split the source in chunks
+	go func() {
+		defer close(jobs)
+		defer close(order)
+		for start := int64(reader.bom); start < size; {
+			end, err := nextBoundary(r, size, split, start, chunkSize)
+			c := chunk{start: start, end: end, result: make(chan chunkResult, 1)}
+			if err != nil {
+				c.result <- chunkResult{err: err}
+				end = size
+			}
+			select {
+			case order <- c.result:
+			case <-done:
+				return
+			}
+			if err == nil {
+				select {
+				case jobs <- c:
+				case <-done:
+					return
+				}
+			}
+			start = end
+		}
+	}()
+
+	// decode the chunks
+	for range workers {
+		go func() {
+			for c := range jobs {
+				c.result <- decodeChunk(r, c.start, c.end, enc, options)
+			}
+		}()
+	}
+
+	// write the output in order
+	var written int64
+	for result := range order {
+		res := <-result
+		if res.err != nil {
+			return written, res.err
+		}
+		n, err := w.Write(res.out)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// parallelSupport returns the error of the options, or an error
+// that reports the options not supported by DecodeParallel, or nil.
+func parallelSupport(p *readerParams) error {
+	if p.err != nil {
+		return p.err
+	}
+	var unsupported []string
+	if p.shared {
+		unsupported = append(unsupported, "the shared transformers (WithTransform)")
+	}
+	if p.maxIn > 0 || p.maxOut > 0 || p.maxRatio > 0 {
+		unsupported = append(unsupported, "the limits")
+	}
+	if p.stats || len(p.hooks) > 0 {
+		unsupported = append(unsupported, "the statistics")
+	}
+	if p.lazy {
+		unsupported = append(unsupported, "the lazy detection")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("utf8reader: %s not supported by DecodeParallel", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// decodeChunk reads and decodes the bytes of r between start and end.
+func decodeChunk(r io.ReaderAt, start, end int64, enc string, options []Option) chunkResult {
+	buf := make([]byte, end-start)
+	if n, err := r.ReadAt(buf, start); n < len(buf) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return chunkResult{err: err}
+	}
+	t := newTransformer(enc, options)
+	if t == nil {
+		return chunkResult{out: buf}
+	}
+	out, _, err := transform.Bytes(t, buf)
+	return chunkResult{out: out, err: err}
+}
@@ -0,0 +1,129 @@
+package utf8reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// sjisText returns about size bytes of Shift_JIS encoded text and its UTF-8 version.
+func sjisText(size int) ([]byte, string) {
+	var sb strings.Builder
+	for i := 0; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "%d: 日本語のテキストです。これはテストです。\n", i)
+	}
+	in, _ := japanese.ShiftJIS.NewEncoder().String(sb.String())
+	return []byte(in), sb.String()
+}
+
+func TestDecodeParallel(t *testing.T) {
+	sjis, sjisOut := sjisText(5 << 20)
+	gb, _ := simplifiedchinese.GB18030.NewEncoder().String(strings.Repeat("这是中文文本。这是一个测试。\n", 200000))
+	data := []struct {
+		name string
+		in   []byte
+		out  string
+	}{
+		{"Shift_JIS", sjis, sjisOut},
+		{"GB18030", []byte(gb), strings.Repeat("这是中文文本。这是一个测试。\n", 200000)},
+		{"UTF-8", []byte(sjisOut), sjisOut},
+		{"small", []byte("C'est b\xeate en fran\xe7ais"), "C'est bête en français"},
+		{"empty", nil, ""},
+	}
+	for _, d := range data {
+		for _, workers := range []int{0, 1, 3} {
+			var out bytes.Buffer
+			n, err := DecodeParallel(&out, bytes.NewReader(d.in), int64(len(d.in)), workers)
+			if err != nil || n != int64(len(d.out)) || out.String() != d.out {
+				t.Errorf("%s: DecodeParallel(%d workers) = %d, %v, want %d, nil", d.name, workers, n, err, len(d.out))
+			}
+		}
+	}
+}
+
+func TestDecodeParallel_hintAliases(t *testing.T) {
+	text := strings.Repeat("Това е ред на български.\n", 100000)
+	le, _ := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder().String(text)
+	be, _ := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().String(text)
+	data := []struct {
+		hint string
+		in   string
+	}{
+		{"UTF-16LE", le},
+		{"utf-16", le},
+		{"unicode", le},
+		{"ucs-2", le},
+		{"utf-16be", be},
+		{"unicodefffe", be},
+	}
+	for _, d := range data {
+		var out bytes.Buffer
+		n, err := DecodeParallel(&out, strings.NewReader(d.in), int64(len(d.in)), 3, WithHint(d.hint))
+		if err != nil || n != int64(len(text)) || out.String() != text {
+			t.Errorf("DecodeParallel(..., WithHint(%q)) = %d, %v, want %d, nil", d.hint, n, err, len(text))
+		}
+	}
+}
+
+func TestDecodeParallel_options(t *testing.T) {
+	in := bytes.Repeat([]byte("C'est b\xeate en fran\xe7ais\r\n"), 100000)
+	var out bytes.Buffer
+	_, err := DecodeParallel(&out, bytes.NewReader(in), int64(len(in)), 4, WithLineEndings(LF), WithCaseFold())
+	if want := strings.Repeat("c'est bête en français\n", 100000); err != nil || out.String() != want {
+		t.Errorf("DecodeParallel(..., WithLineEndings(LF), WithCaseFold()) = %d bytes, %v, want %d bytes", out.Len(), err, len(want))
+	}
+	if _, err := DecodeParallel(&out, bytes.NewReader(in), int64(len(in)), 4, WithPeekSize(0)); err == nil {
+		t.Errorf("DecodeParallel(..., WithPeekSize(0)) error = nil, want non nil")
+	}
+
+	// the sanitizers of WithSanitize are created for each chunk
+	out.Reset()
+	_, err = DecodeParallel(&out, bytes.NewReader(in), int64(len(in)), 4, WithSanitize(StripAll))
+	if want := strings.Repeat("C'est bête en français\r\n", 100000); err != nil || out.String() != want {
+		t.Errorf("DecodeParallel(..., WithSanitize(StripAll)) = %d bytes, %v, want %d bytes", out.Len(), err, len(want))
+	}
+
+	unsupported := map[string]Option{
+		"WithTransform":         WithTransform(cases.Fold(), NewSanitizer(StripAll)),
+		"WithMaxInputBytes":     WithMaxInputBytes(1 << 20),
+		"WithMaxOutputBytes":    WithMaxOutputBytes(1 << 20),
+		"WithMaxExpansionRatio": WithMaxExpansionRatio(2),
+		"WithStats":             WithStats(),
+		"WithHook":              WithHook(new(testHook)),
+		"WithLazyDetection":     WithLazyDetection(),
+	}
+	for name, option := range unsupported {
+		if _, err := DecodeParallel(io.Discard, bytes.NewReader(in), int64(len(in)), 4, option); err == nil {
+			t.Errorf("DecodeParallel(..., %s) error = nil, want non nil", name)
+		}
+	}
+}
+
+func BenchmarkDecodeParallel(b *testing.B) {
+	in, _ := sjisText(32 << 20)
+	b.SetBytes(int64(len(in)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := DecodeParallel(io.Discard, bytes.NewReader(in), int64(len(in)), 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReaderSequential(b *testing.B) {
+	in, _ := sjisText(32 << 20)
+	b.SetBytes(int64(len(in)))
+	b.ResetTimer()
+	for range b.N {
+		if _, err := io.Copy(io.Discard, New(bytes.NewReader(in))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,60 +1,348 @@
 package utf8reader
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/cases"
 	"golang.org/x/text/transform"
-	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
 )
 
 // readerParams contains the parameters for the reader.
 type readerParams struct {
 	peekSize     int                     // The number of bytes to peak
-	transformers []transform.Transformer // The normalization form NFC or NFD
+	maxPeekSize  int                     // The maximal number of bytes to peak in adaptive mode, 0 if not adaptive
+	confidence   int                     // The minimal confidence to stop peeking in adaptive mode
+	lazy         bool                    // The detection is deferred to the first non-ASCII byte
+	samples      int                     // The number of windows sampled from seekable inputs, 0 if no sampling
+	random       bool                    // The sampled windows are at random offsets
+	transformers []transform.Transformer // The normalization form NFC or NFD, and other transformers
+	shared       bool                    // Some transformers are shared by all the readers (see WithTransform)
+	sanitizers   []*Sanitizer            // The sanitizers, to count the sanitized characters
+	maxIn        int64                   // The maximal number of input bytes, 0 if unlimited
+	maxOut       int64                   // The maximal number of output bytes, 0 if unlimited
+	maxRatio     float64                 // The maximal expansion ratio, 0 if unlimited
+	stats        bool                    // The runes, replacement characters and lines are counted
+	hooks        []Hook                  // The hooks that receive the statistics
+	trace        tracer                  // The receiver of the detection events, nil if none
+	hint         string                  // The declared charset, "" if none
+	fallback     string                  // The charset used if the detection is not conclusive, "" if none
+	err          error                   // The errors encountered while setting the options
 }
 
-// option is a functional option for the reader.
-type option func(*readerParams)
+// Option is a functional option for the reader.
+type Option func(*readerParams)
+
+// MinPeekSize and MaxPeekSize are the bounds of the peek size.
+const (
+	MinPeekSize = 1024
+	MaxPeekSize = 16 << 20
+)
 
 // WithPeekSize sets the number of bytes to peak.
 // By default it peaks 4096 bytes.
 // The peaked bytes are used to detect the encoding.
-func WithPeekSize(size int) option {
+// The size should be between MinPeekSize and MaxPeekSize.
+func WithPeekSize(size int) Option {
 	return func(p *readerParams) {
+		if size < MinPeekSize || size > MaxPeekSize {
+			p.addErr(fmt.Errorf("utf8reader: peek size %d out of range [%d, %d]", size, MinPeekSize, MaxPeekSize))
+			return
+		}
 		p.peekSize = size
 	}
 }
 
+// WithAdaptivePeek enables the adaptive peek mode.
+// In this mode, if the first peek (see WithPeekSize) is not decisive,
+// the peek window is doubled until the detection is decisive or maxSize is reached.
+// The detection is decisive if some non-ASCII byte was seen
+// and the confidence is at least the one set by WithMinConfidence.
+// The maxSize should be between MinPeekSize and MaxPeekSize,
+// and not smaller than the peek size.
+func WithAdaptivePeek(maxSize int) Option {
+	return func(p *readerParams) {
+		if maxSize < MinPeekSize || maxSize > MaxPeekSize {
+			p.addErr(fmt.Errorf("utf8reader: max peek size %d out of range [%d, %d]", maxSize, MinPeekSize, MaxPeekSize))
+			return
+		}
+		p.maxPeekSize = maxSize
+	}
+}
+
+// WithMinConfidence sets the minimal confidence, between 1 and 100,
+// that makes the detection decisive in the adaptive peek mode (see WithAdaptivePeek).
+// By default it is 50.
+func WithMinConfidence(confidence int) Option {
+	return func(p *readerParams) {
+		if confidence < 1 || confidence > 100 {
+			p.addErr(fmt.Errorf("utf8reader: confidence %d out of range [1, 100]", confidence))
+			return
+		}
+		p.confidence = confidence
+	}
+}
+
+// WithLazyDetection enables the lazy detection mode.
+// In this mode, if the peeked bytes are all ASCII, the Reader stays in
+// an "ASCII so far" state and the ASCII bytes are passed through.
+// At the first non-ASCII byte, the encoding is detected on a window
+// (of the peek size) starting at this byte and the Reader switches to
+// the corresponding decoder for the rest of the input.
+// The offset of this byte is returned by Reader.SwitchOffset,
+// and the new encoding by Reader.Encoding.
+func WithLazyDetection() Option {
+	return func(p *readerParams) {
+		p.lazy = true
+	}
+}
+
+// WithSampling enables the sampling of seekable inputs.
+// If the input is an io.ReadSeeker, or an io.ReaderAt with a known size
+// (like *os.File, *bytes.Reader or *io.SectionReader), the encoding is detected
+// on n windows (of the peek size) evenly spread over the input:
+// the first one at the start, the last one at the end.
+// For example WithSampling(3) samples the start, the middle and the end of the input.
+// After the detection the input is rewound and read normally.
+// The number of windows n should be between 2 and 64.
+func WithSampling(n int) Option {
+	return func(p *readerParams) {
+		if n < 2 || n > maxSamples {
+			p.addErr(fmt.Errorf("utf8reader: number of samples %d out of range [2, %d]", n, maxSamples))
+			return
+		}
+		p.samples = n
+		p.random = false
+	}
+}
+
+// WithRandomSampling is like WithSampling but the windows,
+// except the first one that is at the start, are at random offsets.
+func WithRandomSampling(n int) Option {
+	return func(p *readerParams) {
+		WithSampling(n)(p)
+		p.random = p.samples > 0
+	}
+}
+
+// WithHint sets the declared charset of the input, like the charset parameter
+// of a Content-Type header. The declared charset is used instead of the detected one, except if:
+//   - the input starts with a BOM,
+//   - the charset is unknown,
+//   - the charset is UTF-8 but the input is not valid UTF-8,
+//   - the input is valid UTF-8 with some non-ASCII characters (a common mislabeling).
+//
+// In these cases the encoding is detected as usual.
+func WithHint(charset string) Option {
+	return func(p *readerParams) {
+		p.hint = strings.TrimSpace(charset)
+	}
+}
+
+// WithFallback sets the charset used when the detection is not conclusive:
+// no encoding was detected, or the confidence of the detected one is below
+// the minimal confidence (see WithMinConfidence). The BOM, the hint (see WithHint)
+// and the ASCII inputs are not concerned.
+// If the charset is unknown, NewReader returns an error.
+func WithFallback(charset string) Option {
+	return func(p *readerParams) {
+		charset = strings.TrimSpace(charset)
+		if lookup(charset) == nil {
+			p.addErr(fmt.Errorf("utf8reader: unknown fallback charset %q", charset))
+			return
+		}
+		p.fallback = charset
+	}
+}
+
 // WithNormalization sets the normalization form.
-// The normalization form can be "NFC" or "NFD".
+// The normalization form can be "NFC", "NFD", "NFKC" or "NFKD",
+// or any comma separated list of transforms accepted by ParseTransform,
+// like "NFKC,fold".
 // By default no normalization is done.
-// WithNormalization("NFC") is equivalent to WithTransformers(norm.NFC).
-// WithNormalization("NFD") is equivalent to WithTransformers(norm.NFD).
-func WithNormalization(nor string) option {
+// WithNormalization("NFC") is equivalent to WithTransform(norm.NFC).
+// WithNormalization("NFD") is equivalent to WithTransform(norm.NFD).
+// If the normalization form is unknown, NewReader returns an error.
+func WithNormalization(nor string) Option {
+	return func(p *readerParams) {
+		trs, err := ParseTransform(nor)
+		if err != nil {
+			p.addErr(err)
+			return
+		}
+		p.transformers = append(p.transformers, trs...)
+	}
+}
+
+// WithCaseFold appends the Unicode case folding transformer.
+func WithCaseFold() Option {
+	return func(p *readerParams) {
+		p.transformers = append(p.transformers, cases.Fold())
+	}
+}
+
+// WithWidthFold appends the width folding transformer,
+// that maps fullwidth and halfwidth runes to their canonical width.
+func WithWidthFold() Option {
+	return func(p *readerParams) {
+		p.transformers = append(p.transformers, width.Fold)
+	}
+}
+
+// WithNarrow appends the transformer that maps runes to their narrow variant.
+func WithNarrow() Option {
+	return func(p *readerParams) {
+		p.transformers = append(p.transformers, width.Narrow)
+	}
+}
+
+// WithWiden appends the transformer that maps runes to their wide variant.
+func WithWiden() Option {
+	return func(p *readerParams) {
+		p.transformers = append(p.transformers, width.Widen)
+	}
+}
+
+// WithNFKCCasefold appends the NFKC_Casefold transformer
+// (the NFKC normalization combined with the case folding).
+func WithNFKCCasefold() Option {
+	return func(p *readerParams) {
+		p.transformers = append(p.transformers, newNFKCCasefold())
+	}
+}
+
+// WithLineEndings appends a transformer that converts all the line endings
+// (CRLF, CR, LF and the Unicode NEL, LINE SEPARATOR and PARAGRAPH SEPARATOR)
+// to le, which is one of LF, CRLF or Native.
+// The conversion is done after the normalization and the previous transformers.
+func WithLineEndings(le LineEnding) Option {
+	return func(p *readerParams) {
+		p.transformers = append(p.transformers, NewLineEndingTransformer(le))
+	}
+}
+
+// WithSanitize appends a Sanitizer that strips, replaces or escapes
+// the control, invisible and bidi characters following the policy.
+// The number of sanitized characters is returned by Reader.Sanitized.
+func WithSanitize(policy SanitizePolicy) Option {
 	return func(p *readerParams) {
-		switch nor {
-		case "NFC":
-			p.transformers = append(p.transformers, norm.NFC)
-		case "NFD":
-			p.transformers = append(p.transformers, norm.NFD)
-		default:
-			panic("only NFC and NFD are supported")
+		if err := policy.validate(); err != nil {
+			p.addErr(err)
+			return
 		}
+		s := NewSanitizer(policy)
+		p.transformers = append(p.transformers, s)
+		p.sanitizers = append(p.sanitizers, s)
+	}
+}
+
+// WithTransliteration appends a transformer that transliterates the text to ASCII,
+// using the lang ("bg", "ru", "uk", "de" or "") rules (see NewTransliterator).
+// If the language is unknown, NewReader returns an error.
+func WithTransliteration(lang string) Option {
+	return func(p *readerParams) {
+		t, err := NewTransliterator(lang)
+		if err != nil {
+			p.addErr(err)
+			return
+		}
+		p.transformers = append(p.transformers, t)
+	}
+}
+
+// WithMaxInputBytes limits the number of bytes read from the input to n.
+// Once more bytes are available, Read returns a *LimitError.
+// The bytes read to sample the input (see WithSampling) are counted.
+// If the limit is exceeded in the peeked or the sampled bytes, NewReader returns the error.
+func WithMaxInputBytes(n int64) Option {
+	return func(p *readerParams) {
+		if n <= 0 {
+			p.addErr(fmt.Errorf("utf8reader: max input bytes %d should be positive", n))
+			return
+		}
+		p.maxIn = n
+	}
+}
+
+// WithMaxOutputBytes limits the number of UTF-8 bytes returned by Read to n.
+// Once more bytes are produced, Read returns a *LimitError,
+// after the bytes before the limit, cut at the last rune boundary.
+func WithMaxOutputBytes(n int64) Option {
+	return func(p *readerParams) {
+		if n <= 0 {
+			p.addErr(fmt.Errorf("utf8reader: max output bytes %d should be positive", n))
+			return
+		}
+		p.maxOut = n
+	}
+}
+
+// WithMaxExpansionRatio limits the number of bytes returned by Read to ratio times
+// the number of input bytes decoded so far (the peeked bytes are not counted before they are decoded).
+// Once more bytes are produced, Read returns a *LimitError.
+// The decoding of a single byte encoding can produce 3 bytes per input byte,
+// and the decomposition (NFD or NFKD) some more.
+func WithMaxExpansionRatio(ratio float64) Option {
+	return func(p *readerParams) {
+		if !(ratio >= 1) {
+			p.addErr(fmt.Errorf("utf8reader: max expansion ratio %g should be at least 1", ratio))
+			return
+		}
+		p.maxRatio = ratio
 	}
 }
 
 // WithTransformers append a (set of) transformer(s).
-func WithTransform(transformers ...transform.Transformer) option {
+// The same transformers are used by all the readers created with the option,
+// so DecodeParallel, that decodes concurrently, does not accept them.
+func WithTransform(transformers ...transform.Transformer) Option {
 	return func(p *readerParams) {
 		p.transformers = append(p.transformers, transformers...)
+		p.shared = p.shared || len(transformers) > 0
 	}
 }
 
 // newParams returns a new readerParams with the options set.
-func newParams(options ...option) *readerParams {
+func newParams(options ...Option) *readerParams {
 	p := &readerParams{
-		peekSize: 4096,
+		peekSize:   4096,
+		confidence: 50,
 	}
 	for _, opt := range options {
 		opt(p)
 	}
+	if p.maxPeekSize > 0 && p.maxPeekSize < p.peekSize {
+		p.addErr(fmt.Errorf("utf8reader: max peek size %d smaller than the peek size %d", p.maxPeekSize, p.peekSize))
+	}
 	return p
 }
+
+// transformer returns the decoder of the encoding enc chained with the transformers,
+// or nil if there is nothing to transform. If the encoding is unknown (enc is empty),
+// the input is left unchanged.
+func (p *readerParams) transformer(enc string) transform.Transformer {
+	if enc == "" {
+		return nil
+	}
+	var trs []transform.Transformer
+	if enc != "UTF-8" {
+		if e := lookup(enc); e != nil {
+			trs = append(trs, e.NewDecoder())
+		}
+	}
+	trs = append(trs, p.transformers...)
+	switch len(trs) {
+	case 0:
+		return nil
+	case 1:
+		return trs[0]
+	}
+	return transform.Chain(trs...)
+}
+
+// addErr records err, in addition to the previous errors.
+func (p *readerParams) addErr(err error) {
+	p.err = errors.Join(p.err, err)
+}
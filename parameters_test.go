@@ -42,3 +42,25 @@ func TestNewParams(t *testing.T) {
 		t.Errorf("newParams(WithPeakSize(8192), WithNormalizationForm(\"NFC\")).transformers = %v, want [NFC]", p.transformers)
 	}
 }
+
+func TestNewParams_err(t *testing.T) {
+	p := newParams(WithNormalization("NFKC,fold"))
+	if p.err != nil {
+		t.Errorf("newParams(WithNormalization(\"NFKC,fold\")).err = %v, want nil", p.err)
+	}
+	if len(p.transformers) != 2 || p.transformers[0] != norm.NFKC {
+		t.Errorf("newParams(WithNormalization(\"NFKC,fold\")).transformers = %v, want [NFKC fold]", p.transformers)
+	}
+	p = newParams(WithNormalization("NFX"))
+	if p.err == nil {
+		t.Errorf("newParams(WithNormalization(\"NFX\")).err = nil, want non nil")
+	}
+	p = newParams(WithFallback(" windows-1252 "))
+	if p.err != nil || p.fallback != "windows-1252" {
+		t.Errorf("newParams(WithFallback(\"windows-1252\")) = %q, %v, want windows-1252, nil", p.fallback, p.err)
+	}
+	p = newParams(WithFallback("latin-42"))
+	if p.err == nil {
+		t.Errorf("newParams(WithFallback(\"latin-42\")).err = nil, want non nil")
+	}
+}
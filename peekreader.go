@@ -58,6 +58,22 @@ func (r *peekReader) peek() []byte {
 	return r.buf
 }
 
+// grow reads at most n more bytes from the underlying reader
+// and appends them to the peek buffer.
+// It returns the number of bytes read, which is less than n at the end of the input.
+// This function should be called before any Read operation.
+func (r *peekReader) grow(n int) (int, error) {
+	l := len(r.buf)
+	buf := make([]byte, l+n)
+	copy(buf, r.buf)
+	n, err := io.ReadFull(r.r, buf[l:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return n, err
+	}
+	r.buf = buf[:l+n]
+	return n, nil
+}
+
 // skip skips at most n bytes from the buffer.
 func (r *peekReader) skip(n int) {
 	if n > len(r.buf) {
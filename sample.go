@@ -0,0 +1,134 @@
+package utf8reader
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"slices"
+)
+
+// maxSamples is the maximal number of sampled windows.
+const maxSamples = 64
+
+// sampleSource returns a function that reads at the given offset,
+// the offset where the input starts and the size of the input,
+// if r is an io.ReadSeeker or an io.ReaderAt with a known size.
+// The function rewind restores the position of r.
+// If r is not seekable, ok is false.
+func sampleSource(r io.Reader) (readAt func(p []byte, off int64) (int, error), start, size int64, rewind func() error, ok bool) {
+	if s, isSeeker := r.(io.ReadSeeker); isSeeker {
+		start, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, 0, nil, false
+		}
+		size, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, 0, nil, false
+		}
+		readAt = func(p []byte, off int64) (int, error) {
+			if _, err := s.Seek(off, io.SeekStart); err != nil {
+				return 0, err
+			}
+			return io.ReadFull(s, p)
+		}
+		rewind = func() error {
+			_, err := s.Seek(start, io.SeekStart)
+			return err
+		}
+		return readAt, start, size, rewind, rewind() == nil
+	}
+	if ra, isReaderAt := r.(io.ReaderAt); isReaderAt {
+		size := int64(-1)
+		switch s := r.(type) {
+		case interface{ Size() int64 }:
+			size = s.Size()
+		case interface{ Stat() (fs.FileInfo, error) }:
+			if fi, err := s.Stat(); err == nil && fi.Mode().IsRegular() {
+				size = fi.Size()
+			}
+		}
+		if size < 0 {
+			return nil, 0, 0, nil, false
+		}
+		return ra.ReadAt, 0, size, func() error { return nil }, true
+	}
+	return nil, 0, 0, nil, false
+}
+
+// sampleOffsets returns n offsets of windows of size w, in [start, size-w],
+// aligned on 4 bytes from start. The first offset is always start.
+// If random is false the offsets are evenly spaced and the last window ends at size.
+func sampleOffsets(n int, w int, start, size int64, random bool) []int64 {
+	span := size - start - int64(w)
+	offsets := []int64{start}
+	for k := 1; k < n; k++ {
+		var off int64
+		if random {
+			off = rand.Int64N(span + 1)
+		} else {
+			off = span * int64(k) / int64(n-1)
+		}
+		offsets = append(offsets, start+off&^3)
+	}
+	slices.Sort(offsets)
+	return slices.Compact(offsets)
+}
+
+// sample reads n windows of size w from r (if it is seekable),
+// and returns the concatenation of the non-ASCII ones, separated by new lines,
+// or the first window if they are all ASCII.
+// The ASCII windows are skipped because they carry no information
+// and dilute the statistics of the detection.
+// The leading UTF-8 continuation bytes and the last truncated rune
+// of all windows, except the first, are trimmed.
+// The position of r is restored.
+// The bytes read are counted by lim, that returns an error if the input limit is exceeded.
+// If r is not seekable or if it is too small to be sampled,
+// or if the first window looks like UTF-16, it returns nil.
+func sample(r io.Reader, n int, w int, random bool, lim *limiter) ([]byte, error) {
+	readAt, start, size, rewind, ok := sampleSource(r)
+	if !ok || size-start <= int64(n*w) {
+		return nil, nil
+	}
+	var first, samples []byte
+	window := make([]byte, w)
+	for i, off := range sampleOffsets(n, w, start, size, random) {
+		m, err := readAt(window, off)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			rewind()
+			return nil, err
+		}
+		if err := lim.sampled(m); err != nil {
+			rewind()
+			return nil, err
+		}
+		win := window[:m]
+		if i == 0 {
+			if bom, _ := detectBOM(win); bom != "" || guessUTF16(win) != "" {
+				// the windows can not be concatenated
+				return nil, rewind()
+			}
+			first = bytes.Clone(win)
+		}
+		if isASCII(win) {
+			continue
+		}
+		if i == 0 {
+			samples = append(samples, win...)
+			continue
+		}
+		// trim the truncated runes at both ends
+		for k := 0; k < 3 && len(win) > 0 && win[0]&0xC0 == 0x80; k++ {
+			win = win[1:]
+		}
+		if len(samples) > 0 {
+			samples = append(samples, '\n')
+		}
+		samples = append(samples, win[:trunc(win)]...)
+	}
+	if samples == nil {
+		samples = first
+	}
+	return samples, rewind()
+}
@@ -0,0 +1,82 @@
+package utf8reader
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readerAt hides all the methods of bytes.Reader except Read, ReadAt and Size.
+type readerAt struct {
+	r *bytes.Reader
+}
+
+func (r readerAt) Read(p []byte) (int, error)              { return r.r.Read(p) }
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) { return r.r.ReadAt(p, off) }
+func (r readerAt) Size() int64                             { return r.r.Size() }
+
+func TestSampleOffsets(t *testing.T) {
+	got := sampleOffsets(3, 100, 0, 1000, false)
+	if len(got) != 3 || got[0] != 0 || got[1] != 448 || got[2] != 900 {
+		t.Errorf("sampleOffsets(3, 100, 0, 1000, false) = %v, want [0 448 900]", got)
+	}
+	for range 10 {
+		got = sampleOffsets(5, 100, 10, 1000, true)
+		for i, off := range got {
+			if off < 10 || off > 900 || (off-10)%4 != 0 || (i > 0 && off <= got[i-1]) {
+				t.Errorf("sampleOffsets(5, 100, 10, 1000, true) = %v, invalid offset %d", got, off)
+			}
+		}
+	}
+}
+
+func TestNew_sampling(t *testing.T) {
+	// ASCII with windows-1251 "Глупаво е на български" at the end
+	text := []byte{0xC3, 0xEB, 0xF3, 0xEF, 0xE0, 0xE2, 0xEE, 0x20, 0xE5, 0x20, 0xED, 0xE0, 0x20, 0xE1, 0xFA, 0xEB, 0xE3, 0xE0, 0xF0, 0xF1, 0xEA, 0xE8, '\n'}
+	in := bytes.Repeat([]byte("0123456789abcdef"), 20000)
+	for range 3000 {
+		in = append(in, text...)
+	}
+	want := strings.Repeat("0123456789abcdef", 20000) + strings.Repeat("Глупаво е на български\n", 3000)
+
+	r := New(bytes.NewReader(in))
+	if r.Encoding() != "UTF-8" {
+		t.Errorf("without sampling Encoding() = %s, want UTF-8", r.Encoding())
+	}
+
+	sources := map[string]func() io.Reader{
+		"io.ReadSeeker": func() io.Reader { return bytes.NewReader(in) },
+		"io.ReaderAt":   func() io.Reader { return readerAt{bytes.NewReader(in)} },
+	}
+	for name, src := range sources {
+		for _, opt := range []Option{WithSampling(3), WithRandomSampling(64)} {
+			r, err := NewReader(src(), opt)
+			if err != nil {
+				t.Fatalf("%s: NewReader(...) error = %v, want nil", name, err)
+			}
+			if strings.ToUpper(r.Encoding()) != "WINDOWS-1251" {
+				t.Errorf("%s: with sampling Encoding() = %s, want windows-1251", name, r.Encoding())
+			}
+			b, err := io.ReadAll(r)
+			if err != nil || string(b) != want {
+				t.Errorf("%s: io.ReadAll(r) = ...%q, %v, want ...%q, nil", name, b[max(0, len(b)-30):], err, want[len(want)-30:])
+			}
+		}
+	}
+
+	// the current position of a seeker is restored
+	br := bytes.NewReader(append([]byte("skipped"), in...))
+	br.Seek(7, io.SeekStart)
+	r, err := NewReader(br, WithSampling(3))
+	if err != nil {
+		t.Fatalf("NewReader(...) error = %v, want nil", err)
+	}
+	if b, err := io.ReadAll(r); err != nil || string(b) != want {
+		t.Errorf("io.ReadAll(r) after seek, err = %v, want the text", err)
+	}
+
+	if _, err := NewReader(bytes.NewReader(in), WithSampling(1)); err == nil {
+		t.Errorf("NewReader(..., WithSampling(1)) error = nil, want non nil")
+	}
+}
@@ -0,0 +1,213 @@
+package utf8reader
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// SanitizeAction is the action applied by a Sanitizer to an unwanted character.
+type SanitizeAction int
+
+const (
+	// SanitizeKeep keeps the character unchanged.
+	SanitizeKeep SanitizeAction = iota
+	// SanitizeStrip removes the character.
+	SanitizeStrip
+	// SanitizeReplace replaces the character by U+FFFD (the replacement character).
+	SanitizeReplace
+	// SanitizeEscape replaces the character by its Go escape sequence, like `\u200B`.
+	SanitizeEscape
+)
+
+// sanitizeActionNames are the names of the sanitize actions.
+var sanitizeActionNames = []string{"keep", "strip", "replace", "escape"}
+
+// String returns the name of the action.
+func (a SanitizeAction) String() string {
+	if a < 0 || int(a) >= len(sanitizeActionNames) {
+		return "SanitizeAction(?)"
+	}
+	return sanitizeActionNames[a]
+}
+
+// ParseSanitizeAction returns the action named s ("keep", "strip", "replace" or "escape"),
+// the case is ignored.
+func ParseSanitizeAction(s string) (SanitizeAction, error) {
+	for a, name := range sanitizeActionNames {
+		if strings.EqualFold(s, name) {
+			return SanitizeAction(a), nil
+		}
+	}
+	return 0, fmt.Errorf("utf8reader: unknown sanitize action %q", s)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (a SanitizeAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (a *SanitizeAction) UnmarshalText(text []byte) error {
+	action, err := ParseSanitizeAction(string(text))
+	if err != nil {
+		return err
+	}
+	*a = action
+	return nil
+}
+
+// SanitizePolicy sets the action to apply to each category of unwanted characters.
+type SanitizePolicy struct {
+	// Control are the C0 controls (including NUL) except TAB, LF and CR,
+	// the DEL character and the C1 controls.
+	Control SanitizeAction `json:"control,omitempty" yaml:"control,omitempty"`
+	// Invisible are the invisible formatting characters, like the zero width space,
+	// the zero width (non) joiner, the word joiner, the soft hyphen and the inner BOM.
+	// Note that the zero width joiner is used in emoji sequences and some scripts.
+	Invisible SanitizeAction `json:"invisible,omitempty" yaml:"invisible,omitempty"`
+	// Bidi are the bidirectional formatting characters (the marks, embeddings,
+	// overrides and isolates) that can be used to hide the real order of a text.
+	Bidi SanitizeAction `json:"bidi,omitempty" yaml:"bidi,omitempty"`
+}
+
+// StripAll is the policy that strips all the unwanted characters.
+var StripAll = SanitizePolicy{
+	Control:   SanitizeStrip,
+	Invisible: SanitizeStrip,
+	Bidi:      SanitizeStrip,
+}
+
+// validate returns an error if some action of the policy is unknown.
+func (p SanitizePolicy) validate() error {
+	for _, a := range []SanitizeAction{p.Control, p.Invisible, p.Bidi} {
+		if a < SanitizeKeep || a > SanitizeEscape {
+			return fmt.Errorf("utf8reader: unknown sanitize action %d", a)
+		}
+	}
+	return nil
+}
+
+// action returns the action that the policy applies to r.
+func (p SanitizePolicy) action(r rune) SanitizeAction {
+	switch {
+	case isControl(r):
+		return p.Control
+	case isInvisible(r):
+		return p.Invisible
+	case isBidi(r):
+		return p.Bidi
+	}
+	return SanitizeKeep
+}
+
+// isControl returns true for the C0 controls except TAB, LF and CR,
+// the DEL character and the C1 controls.
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || (r >= 0x7F && r <= 0x9F)
+}
+
+// isInvisible returns true for the invisible formatting characters.
+func isInvisible(r rune) bool {
+	switch r {
+	case 0x00AD, // soft hyphen
+		0x034F,                 // combining grapheme joiner
+		0x115F, 0x1160, 0x3164, // hangul fillers
+		0xFFA0,         // halfwidth hangul filler
+		0x17B4, 0x17B5, // khmer inherent vowels
+		0x180E, // mongolian vowel separator
+		0xFEFF: // zero width no-break space (BOM)
+		return true
+	}
+	return (r >= 0x200B && r <= 0x200D) || // zero width space, non-joiner and joiner
+		(r >= 0x2060 && r <= 0x2064) // word joiner and invisible operators
+}
+
+// isBidi returns true for the bidirectional formatting characters.
+func isBidi(r rune) bool {
+	return r == 0x061C || // arabic letter mark
+		r == 0x200E || r == 0x200F || // left-to-right and right-to-left marks
+		(r >= 0x202A && r <= 0x202E) || // embeddings and overrides
+		(r >= 0x2066 && r <= 0x2069) // isolates
+}
+
+// Sanitizer is a transformer that strips, replaces or escapes
+// the control, invisible and bidi characters of an UTF-8 encoded text,
+// following a SanitizePolicy.
+type Sanitizer struct {
+	policy SanitizePolicy
+	count  int
+}
+
+// NewSanitizer returns a new Sanitizer that applies the policy.
+func NewSanitizer(policy SanitizePolicy) *Sanitizer {
+	return &Sanitizer{policy: policy}
+}
+
+// Count returns the number of characters that were stripped,
+// replaced or escaped since the last Reset.
+func (s *Sanitizer) Count() int {
+	if s == nil {
+		return 0
+	}
+	return s.count
+}
+
+// Reset implements the transform.Transformer interface.
+// It resets the count.
+func (s *Sanitizer) Reset() {
+	s.count = 0
+}
+
+// Transform implements the transform.Transformer interface.
+func (s *Sanitizer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	var esc [10]byte
+	for nSrc < len(src) {
+		if src[nSrc] < utf8.RuneSelf && src[nSrc] >= 0x20 && src[nSrc] < 0x7F {
+			// fast path for printable ascii
+			if nDst == len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = src[nSrc]
+			nDst++
+			nSrc++
+			continue
+		}
+		if !utf8.FullRune(src[nSrc:]) && !atEOF {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		r, size := utf8.DecodeRune(src[nSrc:])
+		action := SanitizeKeep
+		if r != utf8.RuneError || size > 1 {
+			action = s.policy.action(r)
+		}
+		var out []byte
+		switch action {
+		case SanitizeKeep:
+			out = src[nSrc : nSrc+size]
+		case SanitizeReplace:
+			out = []byte("\uFFFD")
+		case SanitizeEscape:
+			out = escapeRune(esc[:0], r)
+		}
+		if nDst+len(out) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += size
+		if action != SanitizeKeep {
+			s.count++
+		}
+	}
+	return nDst, nSrc, nil
+}
+
+// escapeRune appends the Go escape sequence of r to b.
+func escapeRune(b []byte, r rune) []byte {
+	if r > 0xFFFF {
+		return fmt.Appendf(b, `\U%08X`, r)
+	}
+	return fmt.Appendf(b, `\u%04X`, r)
+}
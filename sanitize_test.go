@@ -0,0 +1,60 @@
+package utf8reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/transform"
+)
+
+func TestSanitizer(t *testing.T) {
+	data := []struct {
+		policy SanitizePolicy
+		in     string
+		out    string
+		count  int
+	}{
+		{StripAll, "a\x00b\u200Bc\u00ADd\u202Ee\u2066f\tg\r\nh", "abcdef\tg\r\nh", 5},
+		{StripAll, "\x7f\u0085\u009f\x01é", "é", 4},
+		{SanitizePolicy{Control: SanitizeReplace}, "a\x00b\u200Bc", "a\uFFFDb\u200Bc", 1},
+		{SanitizePolicy{Bidi: SanitizeEscape}, "if \u202E{\u2066", "if \\u202E{\\u2066", 2},
+		{SanitizePolicy{Invisible: SanitizeEscape}, "a\U000E0001\u200Db", "a\U000E0001\\u200Db", 1},
+		{SanitizePolicy{}, "a\x00\u200B\u202E", "a\x00\u200B\u202E", 0},
+		{StripAll, "invalid \xff\xfe", "invalid \xff\xfe", 0},
+	}
+	for _, d := range data {
+		s := NewSanitizer(d.policy)
+		got, _, err := transform.String(s, d.in)
+		if err != nil || got != d.out {
+			t.Errorf("transform.String(%+v, %q) = %q, %v, want %q, nil", d.policy, d.in, got, err, d.out)
+		}
+		if s.Count() != d.count {
+			t.Errorf("Count() after %q = %d, want %d", d.in, s.Count(), d.count)
+		}
+		// one byte at a time to check the buffer boundaries
+		s = NewSanitizer(d.policy)
+		b, err := io.ReadAll(transform.NewReader(iotest.OneByteReader(strings.NewReader(d.in)), s))
+		if err != nil || string(b) != d.out {
+			t.Errorf("one byte reader %+v on %q = %q, %v, want %q, nil", d.policy, d.in, b, err, d.out)
+		}
+	}
+}
+
+func TestNew_sanitize(t *testing.T) {
+	r := New(strings.NewReader("Hello\u200B, \u202Eworld\x00!"), WithSanitize(StripAll))
+	if r == nil {
+		t.Fatalf("New(..., WithSanitize(StripAll)) = nil, want *Reader")
+	}
+	if b, err := r.Peek(); err != nil || string(b) != "Hello, world!" {
+		t.Errorf("r.Peek() = %q, %v, want \"Hello, world!\", nil", b, err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "Hello, world!" {
+		t.Errorf("io.ReadAll(r) = %q, %v, want \"Hello, world!\", nil", b, err)
+	}
+	if r.Sanitized() != 3 {
+		t.Errorf("r.Sanitized() = %d, want 3", r.Sanitized())
+	}
+}
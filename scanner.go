@@ -0,0 +1,199 @@
+package utf8reader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is the error of the lines that are not valid UTF-8 after the decoding,
+// for example because the encoding was not detected.
+var ErrInvalidUTF8 = errors.New("utf8reader: invalid UTF-8")
+
+// ErrUndecodable is the error of the lines with some bytes that the decoder
+// of the detected encoding can not decode, replaced by U+FFFD.
+var ErrUndecodable = errors.New("utf8reader: undecodable bytes")
+
+// LineError is an error that occurred at some line, numbered from 1.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+// Error returns the error prefixed by the line number.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ScanLines is a bufio.SplitFunc that returns the lines without their end of line marker,
+// which can be CR, LF or CRLF. The last line may have no end of line marker.
+func ScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// CR or CRLF
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// the next byte is needed
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Scanner reads the lines of a text in any encoding, converted to UTF-8 (see New).
+// The encoding is detected once, the lines can end with CR, LF or CRLF
+// and the BOM, if any, is trimmed (see KeepBOM).
+type Scanner struct {
+	r    *Reader
+	in   *bomReader
+	s    *bufio.Scanner
+	line int
+	err  error // the terminal error
+}
+
+// NewScanner returns a Scanner reading the lines of r, converted to UTF-8 with the options.
+// It returns an error if some option is invalid or if the first bytes can not be read (see NewReader).
+func NewScanner(r io.Reader, options ...Option) (*Scanner, error) {
+	reader, err := NewReader(r, options...)
+	if err != nil {
+		return nil, err
+	}
+	in := &bomReader{r: reader}
+	s := bufio.NewScanner(in)
+	s.Split(ScanLines)
+	return &Scanner{r: reader, in: in, s: s}, nil
+}
+
+// KeepBOM keeps the BOM, converted to UTF-8 (U+FEFF), at the start of the first line.
+// It does nothing if the input has no BOM, and must be called before the first Scan.
+func (s *Scanner) KeepBOM() {
+	if s.r.bom > 0 && s.line == 0 {
+		s.in.bom = []byte("\uFEFF")
+	}
+}
+
+// Buffer sets the initial buffer and the maximal line length (see bufio.Scanner.Buffer).
+func (s *Scanner) Buffer(buf []byte, max int) {
+	s.s.Buffer(buf, max)
+}
+
+// Scan advances to the next line, and returns false at the end of the input or on error.
+func (s *Scanner) Scan() bool {
+	if !s.s.Scan() {
+		if err := s.s.Err(); err != nil && s.err == nil {
+			s.err = &LineError{Line: s.line + 1, Err: err}
+		}
+		return false
+	}
+	s.line++
+	return true
+}
+
+// Text returns the current line.
+func (s *Scanner) Text() string {
+	return s.s.Text()
+}
+
+// Bytes returns the current line, that is overwritten by the next call to Scan.
+func (s *Scanner) Bytes() []byte {
+	return s.s.Bytes()
+}
+
+// Line returns the number of the current line, starting at 1.
+func (s *Scanner) Line() int {
+	return s.line
+}
+
+// LineErr returns a *LineError wrapping ErrInvalidUTF8 if the current line
+// is not valid UTF-8, or wrapping ErrUndecodable if the encoding is not UTF-8
+// and the line contains a replacement character U+FFFD, usually produced by the decoder
+// for the bytes that are not valid in the encoding. It returns nil otherwise.
+// The replacement characters of the UTF-8 input are not reported, as they are part of the text.
+func (s *Scanner) LineErr() error {
+	line := s.s.Bytes()
+	if !utf8.Valid(line) {
+		return &LineError{Line: s.line, Err: ErrInvalidUTF8}
+	}
+	if enc := s.Encoding(); enc != "UTF-8" && enc != "" && bytes.Contains(line, replacement) {
+		return &LineError{Line: s.line, Err: ErrUndecodable}
+	}
+	return nil
+}
+
+// Err returns the error that stopped the scanning, as a *LineError, or nil at the end of the input.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Encoding returns the detected encoding (see Reader.Encoding).
+func (s *Scanner) Encoding() string {
+	return s.r.Encoding()
+}
+
+// Lines returns an iterator over the lines of r, converted to UTF-8 with the options (see NewScanner).
+// The invalid lines are yielded with a *LineError wrapping ErrInvalidUTF8 or ErrUndecodable (see Scanner.LineErr),
+// and the error that stops the reading is yielded with an empty line.
+//
+//	for line, err := range utf8reader.Lines(r) {
+//		if err != nil {
+//			log.Print(err)
+//			continue
+//		}
+//		fmt.Println(line)
+//	}
+func Lines(r io.Reader, options ...Option) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		s, err := NewScanner(r, options...)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for s.Scan() {
+			if !yield(s.Text(), s.LineErr()) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
+
+// bomReader reads the bytes of bom, then the ones of r.
+type bomReader struct {
+	bom []byte
+	r   io.Reader
+}
+
+// Read implements the io.Reader interface.
+func (b *bomReader) Read(p []byte) (int, error) {
+	if len(b.bom) > 0 {
+		n := copy(p, b.bom)
+		b.bom = b.bom[n:]
+		return n, nil
+	}
+	return b.r.Read(p)
+}
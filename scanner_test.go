@@ -0,0 +1,152 @@
+package utf8reader
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScanLines(t *testing.T) {
+	in := "a\nb\r\nc\rd\r\r\ne\r"
+	want := []string{"a", "b", "c", "d", "", "e"}
+	for _, oneByte := range []bool{false, true} {
+		var r io.Reader = strings.NewReader(in)
+		if oneByte {
+			r = iotest.OneByteReader(r)
+		}
+		s := bufio.NewScanner(r)
+		s.Split(ScanLines)
+		var got []string
+		for s.Scan() {
+			got = append(got, s.Text())
+		}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("oneByte = %t: lines = %q, want %q", oneByte, got, want)
+		}
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	// windows-1251 with a CRLF and CR line endings
+	in := "\xcf\xfa\xf0\xe2\xe8 \xf0\xe5\xe4\r\n\xc2\xf2\xee\xf0\xe8 \xf0\xe5\xe4\r\xd2\xf0\xe5\xf2\xe8 \xf0\xe5\xe4"
+	s, err := NewScanner(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v, want nil", err)
+	}
+	var got []string
+	for s.Scan() {
+		if s.LineErr() != nil {
+			t.Errorf("line %d: LineErr() = %v, want nil", s.Line(), s.LineErr())
+		}
+		got = append(got, s.Text())
+	}
+	if strings.Join(got, "|") != "Първи ред|Втори ред|Трети ред" || s.Err() != nil || s.Line() != 3 {
+		t.Errorf("lines = %q, %v, %d, want the 3 lines, nil, 3", got, s.Err(), s.Line())
+	}
+	if s.Encoding() != "windows-1251" {
+		t.Errorf("s.Encoding() = %s, want windows-1251", s.Encoding())
+	}
+
+	if _, err := NewScanner(strings.NewReader(in), WithPeekSize(1)); err == nil {
+		t.Errorf("NewScanner(..., WithPeekSize(1)) error = nil, want non nil")
+	}
+}
+
+func TestScanner_LineErr(t *testing.T) {
+	// windows-1251 with the undefined byte 0x98 on the second line
+	in := "\xcf\xfa\xf0\xe2\xe8 \xf0\xe5\xe4\n\xc2\xf2\xee\xf0\xe8 \x98 \xf0\xe5\xe4\n"
+	s, err := NewScanner(strings.NewReader(in), WithHint("windows-1251"))
+	if err != nil {
+		t.Fatalf("NewScanner() error = %v, want nil", err)
+	}
+	var lines []int
+	for s.Scan() {
+		var le *LineError
+		if err := s.LineErr(); errors.As(err, &le) && errors.Is(err, ErrUndecodable) {
+			lines = append(lines, le.Line)
+		}
+	}
+	if len(lines) != 1 || lines[0] != 2 {
+		t.Errorf("undecodable lines = %v, want [2]", lines)
+	}
+
+	// the replacement characters of the UTF-8 input are text
+	s, _ = NewScanner(strings.NewReader("caf\uFFFD\n"))
+	if !s.Scan() || s.LineErr() != nil {
+		t.Errorf("LineErr() = %v, want nil for UTF-8", s.LineErr())
+	}
+}
+
+func TestScanner_KeepBOM(t *testing.T) {
+	data := []struct {
+		in   string
+		want string
+	}{
+		{"\xef\xbb\xbfone\ntwo", "\uFEFFone|two"},
+		{"\xff\xfeo\x00n\x00e\x00", "\uFEFFone"},
+		{"one\ntwo", "one|two"},
+	}
+	for _, d := range data {
+		s, err := NewScanner(strings.NewReader(d.in))
+		if err != nil {
+			t.Fatalf("NewScanner(%q) error = %v, want nil", d.in, err)
+		}
+		s.KeepBOM()
+		var got []string
+		for s.Scan() {
+			got = append(got, s.Text())
+		}
+		if strings.Join(got, "|") != d.want {
+			t.Errorf("lines of %q = %q, want %q", d.in, got, d.want)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	// the BOM is trimmed
+	var got []string
+	for line, err := range Lines(strings.NewReader("\xef\xbb\xbfone\ntwo\n")) {
+		if err != nil {
+			t.Errorf("Lines() error = %v, want nil", err)
+		}
+		got = append(got, line)
+	}
+	if strings.Join(got, "|") != "one|two" {
+		t.Errorf("Lines() = %q, want [one two]", got)
+	}
+
+	// an invalid line after the peek and a read error
+	head := strings.Repeat("café\n", 1000)
+	r := io.MultiReader(strings.NewReader(head+"bad \xff line\nok\n"), iotest.ErrReader(errors.New("boom")))
+	var lines []int
+	var stop error
+	for _, err := range Lines(r) {
+		var le *LineError
+		if errors.As(err, &le) {
+			if errors.Is(err, ErrInvalidUTF8) {
+				lines = append(lines, le.Line)
+			} else {
+				stop = err
+			}
+		}
+	}
+	if len(lines) != 1 || lines[0] != 1001 {
+		t.Errorf("invalid lines = %v, want [1001]", lines)
+	}
+	if stop == nil || stop.Error() != "line 1003: boom" {
+		t.Errorf("stop error = %v, want line 1003: boom", stop)
+	}
+
+	// break
+	n := 0
+	for range Lines(strings.NewReader("a\nb\nc\n")) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("break after %d lines, want 1", n)
+	}
+}
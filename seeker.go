@@ -0,0 +1,258 @@
+package utf8reader
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"golang.org/x/text/transform"
+)
+
+// segmentSize is the approximate size in bytes of the source segments
+// decoded independently by a Seeker. It is a multiple of 4
+// to keep the UTF-16 and UTF-32 segments aligned.
+const segmentSize = 64 << 10
+
+// checkpoint maps an output (UTF-8) offset to a source offset.
+type checkpoint struct {
+	out int64 // the offset in the UTF-8 output
+	src int64 // the offset in the source
+}
+
+// Seeker is a seekable UTF-8 view over a random access source.
+// The source is split in segments at safe boundaries (see newSplitFunc)
+// that are decoded independently and on demand.
+// The index of the segments (a list of checkpoints mapping the output
+// offsets to the source offsets) is built lazily, when the output
+// positions are reached for the first time.
+// The segments end preferably after a new line. If there is no new line,
+// UTF-8 and the fixed width encodings (single byte code pages, UTF-16 and UTF-32)
+// are split at any character, and the other segments are extended.
+// The stateful encodings (like ISO-2022-JP) are decoded as a single segment.
+//
+// The transformers set by the options are applied to each segment separately,
+// so they should not depend on the text before the last new line.
+// A Seeker is safe for concurrent use, but the concurrent calls to Read and Seek
+// share the same offset, so only ReadAt makes sense alongside other calls.
+type Seeker struct {
+	src     io.ReaderAt
+	size    int64
+	enc     string
+	split   splitFunc
+	options []Option
+
+	mu    sync.Mutex
+	index []checkpoint // the starts of the segments, and the end of the last decoded one
+	done  bool         // the index covers the whole source
+	last  int          // the index of the cached segment
+	cache []byte       // the output of the cached segment
+	off   int64        // the offset of Read and Seek
+}
+
+// NewReaderAt returns a Seeker that provides a seekable UTF-8 view
+// of the size bytes of r. The encoding is detected the same way as New does,
+// on the first bytes of r (or on windows sampled over r, see WithSampling).
+// The lazy detection mode (WithLazyDetection) is ignored,
+// and the hooks (WithHook) are not called.
+func NewReaderAt(r io.ReaderAt, size int64, options ...Option) (*Seeker, error) {
+	if r == nil {
+		return nil, errors.New("utf8reader: nil reader")
+	}
+	detectOnly := append(options[:len(options):len(options)], withoutHooks)
+	reader, err := NewReader(io.NewSectionReader(r, 0, size), detectOnly...)
+	if err != nil {
+		return nil, err
+	}
+	return &Seeker{
+		src:     r,
+		size:    size,
+		enc:     reader.enc,
+		split:   newSplitFunc(reader.enc),
+		options: options,
+		index:   []checkpoint{{out: 0, src: int64(reader.bom)}},
+		last:    -1,
+	}, nil
+}
+
+// withoutHooks removes the hooks, that are not called by a Seeker.
+func withoutHooks(p *readerParams) {
+	p.hooks = nil
+}
+
+// Encoding returns the encoding detected from the source.
+func (s *Seeker) Encoding() string {
+	return s.enc
+}
+
+// newTransformer returns a new transformer chain, the decoder of enc followed
+// by the transformers set by the options, or nil if no transformation is needed.
+// The options are applied again, to get fresh (possibly stateful) transformers.
+func newTransformer(enc string, options []Option) transform.Transformer {
+	return newParams(options...).transformer(enc)
+}
+
+// nextBoundary returns the source offset of the end of the segment
+// of (approximately) length bytes, that starts at start.
+// The segment end is the first safe boundary after start+length,
+// or the end of the source.
+func nextBoundary(src io.ReaderAt, size int64, split splitFunc, start, length int64) (int64, error) {
+	window := make([]byte, min(length, segmentSize))
+	for off := start + length; off < size; off += int64(len(window)) {
+		n, err := src.ReadAt(window, off)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := split(window[:n]); i >= 0 {
+			return off + int64(i), nil
+		}
+	}
+	return size, nil
+}
+
+// decode returns the output of the segment i, that should be indexed.
+// The segment i+1 start is added to the index if needed.
+func (s *Seeker) decode(i int) ([]byte, error) {
+	if i == s.last {
+		return s.cache, nil
+	}
+	start := s.index[i].src
+	var end int64
+	if i+1 < len(s.index) {
+		end = s.index[i+1].src
+	} else {
+		var err error
+		if end, err = nextBoundary(s.src, s.size, s.split, start, segmentSize); err != nil {
+			return nil, err
+		}
+	}
+	res := decodeChunk(s.src, start, end, s.enc, s.options)
+	if res.err != nil {
+		return nil, res.err
+	}
+	out := res.out
+	if i+1 == len(s.index) {
+		s.index = append(s.index, checkpoint{out: s.index[i].out + int64(len(out)), src: end})
+		s.done = end >= s.size
+	}
+	s.last, s.cache = i, out
+	return out, nil
+}
+
+// segment returns the index of the segment containing the output offset off,
+// or -1 if off is after the end of the output.
+func (s *Seeker) segment(off int64) (int, error) {
+	for !s.done && s.index[len(s.index)-1].out <= off {
+		if _, err := s.decode(len(s.index) - 1); err != nil {
+			return -1, err
+		}
+	}
+	i := sort.Search(len(s.index), func(i int) bool { return s.index[i].out > off }) - 1
+	if i < 0 || i == len(s.index)-1 {
+		// after the end of the last segment
+		return -1, nil
+	}
+	return i, nil
+}
+
+// ReadAt implements the io.ReaderAt interface.
+// The offset off is in the UTF-8 output.
+func (s *Seeker) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("utf8reader: negative offset")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readAt(p, off)
+}
+
+// readAt is ReadAt, with s.mu held.
+func (s *Seeker) readAt(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		i, err := s.segment(off + int64(n))
+		if err != nil {
+			return n, err
+		}
+		if i < 0 {
+			return n, io.EOF
+		}
+		out, err := s.decode(i)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], out[off+int64(n)-s.index[i].out:])
+	}
+	return n, nil
+}
+
+// Read implements the io.Reader interface.
+func (s *Seeker) Read(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, err = s.readAt(p, s.off)
+	s.off += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements the io.Seeker interface.
+// The offset is in the UTF-8 output.
+// Seeking relative to the end decodes the whole source once, to know the output size.
+func (s *Seeker) Seek(offset int64, whence int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		size, err := s.outputSize()
+		if err != nil {
+			return s.off, err
+		}
+		offset += size
+	default:
+		return s.off, errors.New("utf8reader: invalid whence")
+	}
+	if offset < 0 {
+		return s.off, errors.New("utf8reader: negative position")
+	}
+	s.off = offset
+	return offset, nil
+}
+
+// Size returns the size of the UTF-8 output.
+// The first call decodes the whole source, to build the index.
+func (s *Seeker) Size() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outputSize()
+}
+
+// outputSize is Size, with s.mu held.
+func (s *Seeker) outputSize() (int64, error) {
+	for !s.done {
+		if _, err := s.decode(len(s.index) - 1); err != nil {
+			return 0, err
+		}
+	}
+	return s.index[len(s.index)-1].out, nil
+}
+
+// Checkpoint returns the nearest indexed position at or before the output offset off:
+// the output offset of the start of the segment containing off and the corresponding
+// source offset. It returns io.EOF if off is after the end of the output.
+func (s *Seeker) Checkpoint(off int64) (out, src int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, err := s.segment(off)
+	if err != nil {
+		return 0, 0, err
+	}
+	if i < 0 {
+		return 0, 0, io.EOF
+	}
+	return s.index[i].out, s.index[i].src, nil
+}
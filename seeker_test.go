@@ -0,0 +1,122 @@
+package utf8reader
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestSeeker(t *testing.T) {
+	var sb strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&sb, "%d: Глупаво е на български%s\n", i, strings.Repeat("!", i%50))
+	}
+	text := sb.String()
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String(strings.Repeat("日本語のテキスト\n", 30000))
+	cp1251, _ := charmap.Windows1251.NewEncoder().String(text)
+	utf16le, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(text)
+	// no new line in the UTF-16BE text
+	noNL := strings.ReplaceAll(text, "\n", " 😀")
+	utf16be, _ := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().String(noNL)
+
+	data := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{"windows-1251", cp1251, text},
+		{"UTF-16LE with BOM", utf16le, text},
+		{"UTF-16BE without new lines", utf16be, noNL},
+		{"Shift_JIS", sjis, strings.Repeat("日本語のテキスト\n", 30000)},
+		{"UTF-8", text, text},
+		{"empty", "", ""},
+	}
+	for _, d := range data {
+		s, err := NewReaderAt(strings.NewReader(d.in), int64(len(d.in)))
+		if err != nil {
+			t.Fatalf("%s: NewReaderAt() error = %v, want nil", d.name, err)
+		}
+		// random reads
+		for range 50 {
+			off := rand.IntN(len(d.out) + 1)
+			p := make([]byte, rand.IntN(200000))
+			n, err := s.ReadAt(p, int64(off))
+			want := d.out[off:min(len(d.out), off+len(p))]
+			if string(p[:n]) != want || (n < len(p) && err != io.EOF) {
+				t.Fatalf("%s (%s): ReadAt(%d bytes, %d) = %d, %v, want %d bytes", d.name, s.Encoding(), len(p), off, n, err, len(want))
+			}
+		}
+		// size and seek
+		size, err := s.Size()
+		if err != nil || size != int64(len(d.out)) {
+			t.Errorf("%s: Size() = %d, %v, want %d, nil", d.name, size, err, len(d.out))
+		}
+		if off, err := s.Seek(-10, io.SeekEnd); size >= 10 && (err != nil || off != size-10) {
+			t.Errorf("%s: Seek(-10, io.SeekEnd) = %d, %v, want %d, nil", d.name, off, err, size-10)
+		}
+		s.Seek(0, io.SeekStart)
+		b, err := io.ReadAll(s)
+		if err != nil || string(b) != d.out {
+			t.Errorf("%s: io.ReadAll() = %d bytes, %v, want %d bytes", d.name, len(b), err, len(d.out))
+		}
+	}
+}
+
+func TestSeeker_checkpoint(t *testing.T) {
+	// iso-8859-1 "C'est bête en français\n"
+	in := bytes.Repeat([]byte("C'est b\xeate en fran\xe7ais\n"), 20000)
+	s, err := NewReaderAt(bytes.NewReader(in), int64(len(in)), WithNormalization("NFD"))
+	if err != nil {
+		t.Fatalf("NewReaderAt() error = %v, want nil", err)
+	}
+	out, src, err := s.Checkpoint(200000)
+	if err != nil || out > 200000 || src == 0 || src%23 != 0 {
+		t.Errorf("Checkpoint(200000) = %d, %d, %v, want a line start", out, src, err)
+	}
+	want := norm.NFD.String("C'est bête en français\n")
+	p := make([]byte, len(want))
+	if n, err := s.ReadAt(p, out); err != nil || string(p[:n]) != want {
+		t.Errorf("ReadAt(p, %d) = %q, %v, want the NFD line", out, p[:n], err)
+	}
+	if _, _, err := s.Checkpoint(1 << 30); err != io.EOF {
+		t.Errorf("Checkpoint(1 << 30) error = %v, want io.EOF", err)
+	}
+}
+
+func TestSeeker_concurrent(t *testing.T) {
+	in := strings.Repeat("Глупаво е на български\n", 10000)
+	h := new(testHook)
+	s, err := NewReaderAt(strings.NewReader(in), int64(len(in)), WithHook(h))
+	if err != nil {
+		t.Fatalf("NewReaderAt() error = %v, want nil", err)
+	}
+	if len(h.detected) != 0 {
+		t.Errorf("NewReaderAt(..., WithHook(h)) called Detected %v, want no call", h.detected)
+	}
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 100)
+			for range 100 {
+				s.Seek(int64(i*1000), io.SeekStart)
+				s.Read(buf)
+				s.ReadAt(buf, int64(i*2000))
+			}
+		}()
+	}
+	wg.Wait()
+	if size, err := s.Size(); err != nil || size != int64(len(in)) {
+		t.Errorf("Size() = %d, %v, want %d, nil", size, err, len(in))
+	}
+}
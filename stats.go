@@ -0,0 +1,110 @@
+package utf8reader
+
+import (
+	"bytes"
+	"time"
+)
+
+// Stats are the statistics of a Reader.
+type Stats struct {
+	Encoding      string        // the detected encoding (see Reader.Encoding)
+	BytesIn       int64         // the number of bytes read from the input so far, including the peeked and sampled ones
+	BytesOut      int64         // the number of UTF-8 bytes returned by Read so far
+	Runes         int64         // the number of runes returned by Read so far, see WithStats
+	Replacements  int64         // the number of replacement characters (U+FFFD) returned by Read so far, see WithStats
+	Lines         int64         // the number of line feeds returned by Read so far, see WithStats
+	BOM           int           // the length of the stripped BOM, 0 if none
+	DetectionTime time.Duration // the time spent by NewReader to peek and detect the encoding
+}
+
+// Hook receives the statistics of the Readers, for example to export them as metrics
+// (see the expvarhook package). A Hook can be shared by concurrent Readers,
+// so its methods should be safe for concurrent use.
+type Hook interface {
+	// Detected is called by NewReader once the encoding is detected,
+	// with the detected encoding, possibly empty, and the detection time.
+	Detected(encoding string, d time.Duration)
+	// Done is called once, when Read returns a non-nil error,
+	// which is io.EOF at the end of the input.
+	Done(s Stats, err error)
+}
+
+// WithStats enables the counting of the runes, the replacement characters
+// and the lines returned by Read (see Reader.Stats).
+// This counting scans the output, so it is not enabled by default.
+func WithStats() Option {
+	return func(p *readerParams) {
+		p.stats = true
+	}
+}
+
+// WithHook adds a hook that receives the statistics of the Reader.
+// It enables the counting of the runes, the replacement characters and the lines (see WithStats).
+func WithHook(h Hook) Option {
+	return func(p *readerParams) {
+		p.hooks = append(p.hooks, h)
+		p.stats = true
+	}
+}
+
+// Stats returns the statistics of the reader so far.
+func (r *Reader) Stats() Stats {
+	if r == nil {
+		return Stats{}
+	}
+	s := Stats{
+		Encoding:      r.Encoding(),
+		BOM:           r.bom,
+		DetectionTime: r.detection,
+	}
+	if r.lim != nil {
+		s.BytesIn = r.lim.in
+		s.BytesOut = r.lim.stats.bytes
+		s.Runes = r.lim.stats.runes
+		s.Replacements = r.lim.stats.replacements
+		s.Lines = r.lim.stats.lines
+	}
+	return s
+}
+
+// replacement is the UTF-8 encoding of the replacement character U+FFFD.
+var replacement = []byte("\uFFFD")
+
+// counts are the statistics of a UTF-8 stream.
+// The runes, the replacement characters and the lines are counted only if scan is set.
+type counts struct {
+	scan         bool
+	bytes        int64
+	runes        int64
+	replacements int64
+	lines        int64
+	tail         []byte // the last bytes, to find the replacement characters split between two calls
+}
+
+// count updates the statistics with the next bytes b of the stream.
+func (c *counts) count(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	c.bytes += int64(len(b))
+	if !c.scan {
+		return
+	}
+	for _, x := range b {
+		if x&0xC0 != 0x80 {
+			c.runes++
+		}
+	}
+	c.lines += int64(bytes.Count(b, []byte{'\n'}))
+	c.replacements += int64(bytes.Count(b, replacement))
+	// the replacement characters split between the tail and b
+	n := len(replacement) - 1
+	join := append(c.tail, b[:min(len(b), n)]...)
+	c.replacements += int64(bytes.Count(join, replacement))
+	if len(b) >= n {
+		c.tail = append(c.tail[:0], b[len(b)-n:]...)
+	} else {
+		// the tail and b
+		c.tail = append(c.tail[:0], join[max(0, len(join)-n):]...)
+	}
+}
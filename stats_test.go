@@ -0,0 +1,97 @@
+package utf8reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReader_Stats(t *testing.T) {
+	// UTF-16LE with a BOM, two lines and an unpaired surrogate
+	in := "\xff\xfea\x00\xe9\x00\n\x00\x00\xd8b\x00\n\x00"
+	want := Stats{Encoding: "UTF-16LE", BytesIn: 14, BytesOut: 9, Runes: 6, Replacements: 1, Lines: 2, BOM: 2}
+
+	for _, oneByte := range []bool{false, true} {
+		r, err := NewReader(strings.NewReader(in), WithStats())
+		if err != nil {
+			t.Fatalf("NewReader(..., WithStats()) error = %v, want nil", err)
+		}
+		var out io.Reader = r
+		if oneByte {
+			out = iotest.OneByteReader(r)
+		}
+		if b, err := io.ReadAll(out); err != nil || string(b) != "aé\n\uFFFDb\n" {
+			t.Fatalf("io.ReadAll(r) = %q, %v, want \"aé\\n\\uFFFDb\\n\", nil", b, err)
+		}
+		s := r.Stats()
+		if s.DetectionTime <= 0 {
+			t.Errorf("r.Stats().DetectionTime = %v, want positive", s.DetectionTime)
+		}
+		s.DetectionTime = 0
+		if s != want {
+			t.Errorf("oneByte = %t: r.Stats() = %+v, want %+v", oneByte, s, want)
+		}
+	}
+
+	// without WithStats only the bytes are counted
+	r := New(strings.NewReader(in))
+	io.ReadAll(r)
+	if s := r.Stats(); s.BytesIn != 14 || s.BytesOut != 9 || s.Runes != 0 {
+		t.Errorf("r.Stats() = %+v, want 14 bytes in, 9 bytes out and no runes", s)
+	}
+	if s := (*Reader)(nil).Stats(); s != (Stats{}) {
+		t.Errorf("nil.Stats() = %+v, want zero", s)
+	}
+}
+
+func TestCounts_split(t *testing.T) {
+	// a replacement character split at offset 1 and 2 between chunks of several bytes
+	for _, chunks := range [][]string{
+		{"abc\xEF", "\xBF\xBDxyz"},
+		{"abc\xEF\xBF", "\xBDxyz"},
+		{"abc\xEF", "\xBF", "\xBDxyz\xEF\xBF", "\xBD"},
+	} {
+		c := counts{scan: true}
+		for _, b := range chunks {
+			c.count([]byte(b))
+		}
+		want := int64(strings.Count(strings.Join(chunks, ""), "\uFFFD"))
+		if c.replacements != want {
+			t.Errorf("replacements of %q = %d, want %d", chunks, c.replacements, want)
+		}
+	}
+}
+
+// testHook records the calls.
+type testHook struct {
+	detected []string
+	done     []Stats
+	errs     []error
+}
+
+func (h *testHook) Detected(encoding string, d time.Duration) {
+	h.detected = append(h.detected, encoding)
+}
+
+func (h *testHook) Done(s Stats, err error) {
+	h.done = append(h.done, s)
+	h.errs = append(h.errs, err)
+}
+
+func TestWithHook(t *testing.T) {
+	h := new(testHook)
+	r, err := NewReader(strings.NewReader("caf\xe9\n"), WithHook(h))
+	if err != nil {
+		t.Fatalf("NewReader(..., WithHook(h)) error = %v, want nil", err)
+	}
+	if len(h.detected) != 1 || len(h.done) != 0 {
+		t.Fatalf("after NewReader: %d Detected and %d Done calls, want 1 and 0", len(h.detected), len(h.done))
+	}
+	io.ReadAll(r)
+	r.Read(make([]byte, 10))
+	if len(h.done) != 1 || h.errs[0] != io.EOF || h.done[0].Runes != 5 || h.done[0].Lines != 1 {
+		t.Errorf("after reading: Done calls = %+v, %v, want a single call with 5 runes, 1 line and io.EOF", h.done, h.errs)
+	}
+}
@@ -0,0 +1,58 @@
+package utf8reader
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Text is a nullable string column value, like sql.NullString, that is converted
+// to UTF-8 when scanned. It implements sql.Scanner and driver.Valuer.
+// The options of the column, like the hint and the fallback of a legacy latin1 column
+// that mixes Windows-1252 and UTF-8 values, are set before scanning:
+//
+//	name := utf8reader.Text{Options: []utf8reader.Option{
+//		utf8reader.WithHint("windows-1252"),
+//		utf8reader.WithFallback("windows-1252"),
+//	}}
+//	err := row.Scan(&name)
+//
+// Each value is detected alone (see DecodeBytes); use a Detector to detect
+// a single encoding for all the values of a column.
+type Text struct {
+	String   string   // the value converted to UTF-8
+	Valid    bool     // the value is not NULL
+	Encoding string   // the detected encoding of the scanned value, "" if unknown or NULL
+	Options  []Option // the options used to convert the scanned values
+}
+
+// Scan implements the sql.Scanner interface.
+// It converts a []byte or a string value to UTF-8.
+func (t *Text) Scan(value any) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		t.String, t.Valid, t.Encoding = "", false, ""
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("utf8reader: cannot scan %T into Text", value)
+	}
+	out, det, err := DecodeBytes(b, t.Options...)
+	if err != nil {
+		return err
+	}
+	t.String, t.Valid, t.Encoding = string(out), true, det.Encoding
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+// It returns the UTF-8 string, or nil if the value is NULL.
+func (t Text) Value() (driver.Value, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return t.String, nil
+}
@@ -0,0 +1,57 @@
+package utf8reader
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = (*Text)(nil)
+	_ driver.Valuer = Text{}
+)
+
+func TestText_Scan(t *testing.T) {
+	latin1 := []Option{WithHint("windows-1252"), WithFallback("windows-1252")}
+	tests := []struct {
+		value   any
+		options []Option
+		want    Text
+	}{
+		{nil, nil, Text{}},
+		{"plain", nil, Text{String: "plain", Valid: true, Encoding: "UTF-8"}},
+		{[]byte("Café"), latin1, Text{String: "Café", Valid: true, Encoding: "UTF-8"}},
+		{[]byte("Caf\xe9"), latin1, Text{String: "Café", Valid: true, Encoding: "windows-1252"}},
+		{"Caf\xe9 \x80", latin1, Text{String: "Café €", Valid: true, Encoding: "windows-1252"}},
+		{[]byte("Caf\xe9"), []Option{WithFallback("windows-1252")}, Text{String: "Café", Valid: true, Encoding: "windows-1252"}},
+		{[]byte("\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff"), []Option{WithHint("cp1251")}, Text{String: "България", Valid: true, Encoding: "cp1251"}},
+	}
+	for _, tt := range tests {
+		got := Text{String: "previous", Valid: true, Options: tt.options}
+		if err := got.Scan(tt.value); err != nil {
+			t.Errorf("Scan(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got.String != tt.want.String || got.Valid != tt.want.Valid || got.Encoding != tt.want.Encoding {
+			t.Errorf("Scan(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+
+	var txt Text
+	if err := txt.Scan(42); err == nil {
+		t.Errorf("Scan(42) error = nil, want non nil")
+	}
+	txt.Options = []Option{WithNormalization("NFX")}
+	if err := txt.Scan("plain"); err == nil {
+		t.Errorf("Scan() with an invalid option error = nil, want non nil")
+	}
+}
+
+func TestText_Value(t *testing.T) {
+	if v, err := (Text{}).Value(); v != nil || err != nil {
+		t.Errorf("Value() of NULL = %v, %v, want nil, nil", v, err)
+	}
+	if v, err := (Text{String: "Café", Valid: true}).Value(); v != "Café" || err != nil {
+		t.Errorf("Value() = %v, %v, want Café, nil", v, err)
+	}
+}
@@ -0,0 +1,74 @@
+package utf8reader
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Event is a step of the encoding detection, reported by WithTrace and WithLogger.
+// The steps are:
+//   - "peek": the first bytes are read,
+//   - "sample": the windows of a seekable input are read (see WithSampling),
+//   - "adaptive": the peek window grows (see WithAdaptivePeek),
+//   - "bom": the byte order mark is checked,
+//   - "hint": the declared charset is used or ignored (see WithHint),
+//   - "classify": the single pass classification of the bytes
+//     (ASCII, valid UTF-8 and the null pairs counted to guess UTF-16),
+//   - "chardet": the candidates ranked by the statistical detector,
+//   - "decision": the encoding chosen for the bytes, with the reason,
+//   - "fallback": the fallback charset replaces an inconclusive detection (see WithFallback),
+//   - "lazy": the detection is deferred, or done at the first non-ASCII byte
+//     (see WithLazyDetection),
+//   - "result": the encoding used by the Reader.
+type Event struct {
+	Step    string      // the step, like "bom" or "chardet"
+	Message string      // a human readable description of the step
+	Attrs   []slog.Attr // the details of the step
+}
+
+// String returns the step, the message and the attributes of the event in a single line,
+// like `bom: no byte order mark`.
+func (e Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Step, e.Message)
+	for _, a := range e.Attrs {
+		fmt.Fprintf(&b, " %s=%s", a.Key, a.Value)
+	}
+	return b.String()
+}
+
+// tracer receives the events, it is nil if there is no trace.
+type tracer func(Event)
+
+// emit sends an event to t, if any.
+func (t tracer) emit(step, message string, attrs ...slog.Attr) {
+	if t != nil {
+		t(Event{Step: step, Message: message, Attrs: attrs})
+	}
+}
+
+// WithTrace calls f for each step of the encoding detection,
+// to explain why an encoding was chosen.
+func WithTrace(f func(Event)) Option {
+	return func(p *readerParams) {
+		if prev := p.trace; prev != nil {
+			p.trace = func(e Event) {
+				prev(e)
+				f(e)
+			}
+			return
+		}
+		p.trace = f
+	}
+}
+
+// WithLogger logs each step of the encoding detection (see WithTrace)
+// at the debug level, with the step in the "step" attribute.
+func WithLogger(l *slog.Logger) Option {
+	return WithTrace(func(e Event) {
+		attrs := append([]slog.Attr{slog.String("step", e.Step)}, e.Attrs...)
+		l.LogAttrs(context.Background(), slog.LevelDebug, e.Message, attrs...)
+	})
+}
@@ -0,0 +1,79 @@
+package utf8reader
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// steps returns the steps of the events.
+func steps(events []Event) string {
+	var s []string
+	for _, e := range events {
+		s = append(s, e.Step)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestWithTrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		options []Option
+		steps   string
+		last    string // the last decision
+	}{
+		{"bom", "\xef\xbb\xbfcafé", nil, "peek,bom,result", ""},
+		{"ascii", "plain text", nil, "peek,bom,classify,decision,result", "ASCII, compatible with any ASCII based encoding encoding=UTF-8 confidence=0"},
+		{"utf-8", "café", nil, "peek,bom,classify,decision,result", "valid UTF-8 with non-ASCII characters encoding=UTF-8 confidence=100"},
+		{"utf-16", "b\x00\xe9\x00t\x00\xe0\x00", nil, "peek,bom,classify,decision,result", "invalid UTF-8 with null pairs encoding=UTF-16LE confidence=100"},
+		{"chardet", "caf\xe9 cr\xe8me br\xfbl\xe9e", nil, "peek,bom,classify,chardet,decision,result", "best chardet candidate encoding=ISO-8859-1"},
+		{"lazy", "plain text", []Option{WithLazyDetection()}, "peek,bom,classify,decision,lazy,result", ""},
+		// only the final detection is traced
+		{"adaptive", strings.Repeat("plain text\n", 1000), []Option{WithAdaptivePeek(1 << 16)}, "peek,adaptive,adaptive,bom,classify,decision,result", "ASCII, compatible with any ASCII based encoding"},
+	}
+	for _, tt := range tests {
+		var events []Event
+		r, err := NewReader(strings.NewReader(tt.in), append(tt.options, WithTrace(func(e Event) {
+			events = append(events, e)
+		}))...)
+		if err != nil {
+			t.Fatalf("%s: NewReader() error = %v, want nil", tt.name, err)
+		}
+		if got := steps(events); got != tt.steps {
+			t.Errorf("%s: steps = %s, want %s", tt.name, got, tt.steps)
+		}
+		if last := events[len(events)-1]; last.Step != "result" || last.Attrs[0].Value.String() != r.Encoding() {
+			t.Errorf("%s: last event = %s, want the result %s", tt.name, last, r.Encoding())
+		}
+		for _, e := range events {
+			if e.Step == "decision" && tt.last != "" && !strings.HasPrefix(e.String(), "decision: "+tt.last) {
+				t.Errorf("%s: decision = %q, want %q", tt.name, e, "decision: "+tt.last)
+			}
+		}
+	}
+}
+
+func TestWithTrace_lazySwitch(t *testing.T) {
+	in := strings.Repeat("plain text\n", 200) + "caf\xe9 cr\xe8me br\xfbl\xe9e\n"
+	var events []Event
+	r := New(strings.NewReader(in), WithPeekSize(MinPeekSize), WithLazyDetection(), WithTrace(func(e Event) {
+		events = append(events, e)
+	}))
+	r.Read(make([]byte, len(in)))
+	r.Read(make([]byte, len(in)))
+	if last := events[len(events)-1]; last.Step != "lazy" || !strings.Contains(last.String(), "offset=2203") {
+		t.Errorf("last event = %s, want the lazy switch at 2203", last)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	var n int
+	New(strings.NewReader("café"), WithLogger(l), WithTrace(func(Event) { n++ }))
+	if n != 5 || strings.Count(buf.String(), "\n") != 5 || !strings.Contains(buf.String(), "step=decision encoding=UTF-8") {
+		t.Errorf("%d events and the log\n%s\nwant 5 events and 5 log lines", n, buf.String())
+	}
+}
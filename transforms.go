@@ -0,0 +1,83 @@
+package utf8reader
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// namedTransformers maps the (lower case) transform names accepted by
+// ParseTransform to functions returning a fresh transformer.
+// Some transformers (like the case folder) are stateful,
+// so a new one is created for each Reader.
+var namedTransformers = map[string]func() transform.Transformer{
+	"nfc":           func() transform.Transformer { return norm.NFC },
+	"nfd":           func() transform.Transformer { return norm.NFD },
+	"nfkc":          func() transform.Transformer { return norm.NFKC },
+	"nfkd":          func() transform.Transformer { return norm.NFKD },
+	"fold":          func() transform.Transformer { return cases.Fold() },
+	"width":         func() transform.Transformer { return width.Fold },
+	"narrow":        func() transform.Transformer { return width.Narrow },
+	"widen":         func() transform.Transformer { return width.Widen },
+	"nfkc_casefold": newNFKCCasefold,
+}
+
+// newNFKCCasefold returns a transformer that approximates
+// the Unicode NFKC_Casefold mapping: compatibility decomposition,
+// case folding, removal of default ignorable code points
+// and finally compatibility composition.
+func newNFKCCasefold() transform.Transformer {
+	return transform.Chain(
+		norm.NFKD,
+		cases.Fold(),
+		runes.Remove(runes.Predicate(isDefaultIgnorable)),
+		norm.NFKC,
+	)
+}
+
+// isDefaultIgnorable approximates the Unicode Default_Ignorable_Code_Point property
+// as the Other_Default_Ignorable_Code_Point runes, the variation selectors
+// and the format (Cf) runes, except the prepended concatenation marks.
+func isDefaultIgnorable(r rune) bool {
+	switch {
+	case unicode.Is(unicode.Other_Default_Ignorable_Code_Point, r),
+		unicode.Is(unicode.Variation_Selector, r):
+		return true
+	case (r >= 0x0600 && r <= 0x0605) || r == 0x06DD || r == 0x070F || r == 0x08E2 ||
+		r == 0x110BD || r == 0x110CD || (r >= 0xFFF9 && r <= 0xFFFB):
+		return false
+	}
+	return unicode.Is(unicode.Cf, r)
+}
+
+// ParseTransform parses a comma separated list of transform names
+// and returns the corresponding transformers in the same order.
+// The names are case insensitive and can be:
+//   - "NFC", "NFD", "NFKC", "NFKD" for the Unicode normalization forms,
+//   - "fold" for the Unicode case folding,
+//   - "width", "narrow", "widen" for the width folding (see golang.org/x/text/width),
+//   - "NFKC_Casefold" for the combined NFKC normalization and case folding.
+//
+// For example ParseTransform("NFKC,fold") returns [norm.NFKC, cases.Fold()].
+// An error is returned if some name is unknown.
+func ParseTransform(spec string) ([]transform.Transformer, error) {
+	var trs []transform.Transformer
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		newTransformer, ok := namedTransformers[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("utf8reader: unknown transform %q", name)
+		}
+		trs = append(trs, newTransformer())
+	}
+	return trs, nil
+}
@@ -0,0 +1,65 @@
+package utf8reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+)
+
+func TestParseTransform(t *testing.T) {
+	data := []struct {
+		spec string
+		in   string
+		out  string
+	}{
+		{"NFC", "bête", "bête"},
+		{"nfd", "bête", "bête"},
+		{"NFKC", "ﬁ①", "fi1"},
+		{"NFKD", "ﬁê", "fiê"},
+		{"fold", "BÊTE Straße", "bête strasse"},
+		{"width", "ＡＢＣｱ", "ABCア"},
+		{"narrow", "ＡＢＣア", "ABCｱ"},
+		{"widen", "ABC", "ＡＢＣ"},
+		{"NFKC,fold", "ＢÊＴＥ", "bête"},
+		{" NFKC , fold ", "ＢÊＴＥ", "bête"},
+		{"NFKC_Casefold", "ＢＥ\u00ADＴＥ", "bete"},
+		{"", "ＢÊＴＥ", "ＢÊＴＥ"},
+	}
+	for _, d := range data {
+		trs, err := ParseTransform(d.spec)
+		if err != nil {
+			t.Errorf("ParseTransform(%q) error = %v, want nil", d.spec, err)
+			continue
+		}
+		got, _, err := transform.String(transform.Chain(trs...), d.in)
+		if err != nil {
+			t.Errorf("ParseTransform(%q) transform error = %v, want nil", d.spec, err)
+		}
+		if got != d.out {
+			t.Errorf("ParseTransform(%q) on %q = %q, want %q", d.spec, d.in, got, d.out)
+		}
+	}
+
+	for _, spec := range []string{"NFX", "NFC,unknown"} {
+		if _, err := ParseTransform(spec); err == nil {
+			t.Errorf("ParseTransform(%q) error = nil, want non nil", spec)
+		}
+	}
+}
+
+func TestNew_transforms(t *testing.T) {
+	r := New(strings.NewReader("ＢÊＴＥ"), WithNormalization("NFKC"), WithCaseFold())
+	if r == nil {
+		t.Fatalf("New(..., WithNormalization(\"NFKC\"), WithCaseFold()) = nil, want *Reader")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "bête" {
+		t.Errorf("io.ReadAll(r) = %q, %v, want \"bête\", nil", b, err)
+	}
+
+	if r := New(strings.NewReader("test"), WithNormalization("NFX")); r != nil {
+		t.Errorf("New(..., WithNormalization(\"NFX\")) = %v, want nil", r)
+	}
+}
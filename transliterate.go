@@ -0,0 +1,245 @@
+package utf8reader
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// transliterationTables contains the per-language tables, indexed by language code.
+// The tables map lower case runes to their (lower case) ASCII transliteration.
+// The runes that are not in the language table are transliterated with genericTable
+// or by stripping their diacritics.
+var transliterationTables = map[string]transliterationTable{
+	// Bulgarian, the Streamlined System (official since 2009)
+	"bg": {table: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+		'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+		'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts", 'ч': "ch",
+		'ш': "sh", 'щ': "sht", 'ъ': "a", 'ь': "y", 'ю': "yu", 'я': "ya",
+	}, final: map[[2]rune]string{
+		{'и', 'я'}: "a",
+	}},
+	// Russian, the ICAO Doc 9303 romanization (used in passports since 2013)
+	"ru": {table: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+		'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+		'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+		'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+		'я': "ia",
+	}},
+	// Ukrainian, the national romanization (official since 2010)
+	"uk": {table: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie",
+		'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l",
+		'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+		'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu",
+		'я': "ia", '\'': "", '’': "", 'ʼ': "",
+	}, initial: map[rune]string{
+		'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya",
+	}, after: map[[2]rune]string{
+		{'з', 'г'}: "gh",
+	}},
+	// German, the umlaut expansion
+	"de": {table: map[rune]string{
+		'ä': "ae", 'ö': "oe", 'ü': "ue", 'ß': "ss",
+	}},
+	// the generic transliteration only
+	"":        {},
+	"generic": {},
+}
+
+// genericTable contains the transliteration of the runes
+// that do not decompose to an ASCII rune and some diacritic marks.
+var genericTable = map[rune]string{
+	// latin
+	'æ': "ae", 'ð': "d", 'ø': "o", 'þ': "th", 'ß': "ss", 'đ': "d", 'ħ': "h", 'ı': "i",
+	'ĳ': "ij", 'ĸ': "k", 'ŀ': "l", 'ł': "l", 'ŉ': "n", 'ŋ': "ng", 'œ': "oe", 'ŧ': "t",
+	'ƒ': "f", 'ſ': "s",
+	// cyrillic, mostly the ICAO Doc 9303 romanization
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	'ђ': "d", 'є': "ie", 'ѕ': "dz", 'і': "i", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c",
+	'џ': "dz", 'ґ': "g",
+	// greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	// punctuation and spaces
+	'‘': "'", '’': "'", '‚': "'", '“': "\"", '”': "\"", '„': "\"", '«': "\"", '»': "\"",
+	'‹': "'", '›': "'", '‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-",
+	'…': "...", '•': "*", '·': ".", '×': "x", '÷': "/", '€': "EUR", '£': "GBP",
+	'©': "(c)", '®': "(r)", '™': "(tm)", '°': "o",
+	'\u00A0': " ", '\u2007': " ", '\u2009': " ", '\u202F': " ",
+}
+
+// transliterationTable is a language specific transliteration table.
+// The word initial forms are used at the beginning of a word,
+// the after forms after a given rune (like "зг" → "zgh" in Ukrainian),
+// and the final forms after a given rune at the end of a word
+// (like "ия" → "ia" in Bulgarian).
+type transliterationTable struct {
+	table   map[rune]string
+	initial map[rune]string
+	after   map[[2]rune]string
+	final   map[[2]rune]string
+}
+
+// transliterator is a transformer that transliterates UTF-8 text to ASCII.
+// inWord is true if the previous rune was a letter (or an apostrophe inside a word),
+// prev is the previous rune in lower case and prevUpper is true if it was upper case.
+type transliterator struct {
+	transliterationTable
+	inWord    bool
+	prev      rune
+	prevUpper bool
+}
+
+// NewTransliterator returns a transformer that transliterates UTF-8 encoded text to ASCII.
+// The language can be:
+//   - "bg" for the Bulgarian Streamlined System,
+//   - "ru" for the Russian ICAO romanization,
+//   - "uk" for the Ukrainian national romanization,
+//   - "de" for the German umlaut expansion (ä → ae),
+//   - "" or "generic" for the generic transliteration.
+//
+// For all languages the runes that are not in the language table are transliterated
+// by removing their diacritics (NFD decomposition and removal of the marks)
+// and using a generic table for the other common letters and punctuation.
+// The remaining non ASCII runes are replaced by "?".
+// The transliteration of an upper case letter is capitalized (Ж → Zh),
+// or upper cased inside an upper case word (ЖАБА → ZHABA).
+// An error is returned if the language is unknown.
+func NewTransliterator(lang string) (transform.Transformer, error) {
+	t, ok := transliterationTables[strings.ToLower(lang)]
+	if !ok {
+		return nil, fmt.Errorf("utf8reader: unknown transliteration language %q", lang)
+	}
+	return &transliterator{transliterationTable: t}, nil
+}
+
+// Reset implements the transform.Transformer interface.
+func (t *transliterator) Reset() {
+	t.inWord, t.prev, t.prevUpper = false, 0, false
+}
+
+// Transform implements the transform.Transformer interface.
+func (t *transliterator) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	var buf [16]byte
+	for nSrc < len(src) {
+		c := src[nSrc]
+		if c < utf8.RuneSelf && c != '\'' {
+			if nDst == len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = c
+			nDst++
+			nSrc++
+			t.inWord = unicode.IsLetter(rune(c))
+			t.prev, t.prevUpper = unicode.ToLower(rune(c)), 'A' <= c && c <= 'Z'
+			continue
+		}
+		if !utf8.FullRune(src[nSrc:]) && !atEOF {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		r, size := utf8.DecodeRune(src[nSrc:])
+		lower := unicode.ToLower(r)
+		// the next rune is needed for the case of the upper case letters and the final forms
+		next := rune(-1)
+		if _, final := t.final[[2]rune{t.prev, lower}]; final || lower != r && !t.prevUpper {
+			var ok bool
+			if next, ok = nextRune(src[nSrc+size:], atEOF); !ok {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+		}
+		out := t.transliterate(buf[:0], r, next)
+		if nDst+len(out) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += size
+		if !isApostrophe(r) {
+			t.inWord = unicode.IsLetter(r)
+		}
+		t.prev, t.prevUpper = lower, lower != r
+	}
+	return nDst, nSrc, nil
+}
+
+// nextRune returns the rune at the start of src, or -1 if src is empty at the end of the input.
+// It returns false if more input is needed.
+func nextRune(src []byte, atEOF bool) (rune, bool) {
+	if len(src) == 0 {
+		return -1, atEOF
+	}
+	if !utf8.FullRune(src) && !atEOF {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRune(src)
+	return r, true
+}
+
+// transliterate appends the transliteration of r, followed by next, to b.
+func (t *transliterator) transliterate(b []byte, r, next rune) []byte {
+	if r == utf8.RuneError {
+		return append(b, '?')
+	}
+	lower := unicode.ToLower(r)
+	s, ok := t.after[[2]rune{t.prev, lower}]
+	if !ok && !unicode.IsLetter(next) {
+		s, ok = t.final[[2]rune{t.prev, lower}]
+	}
+	if !ok && !t.inWord {
+		s, ok = t.initial[lower]
+	}
+	if !ok {
+		s, ok = t.table[lower]
+	}
+	if !ok {
+		s, ok = genericTable[lower]
+	}
+	if ok {
+		start := len(b)
+		b = append(b, s...)
+		if lower != r && len(b) > start {
+			if t.prevUpper || unicode.IsUpper(next) {
+				// inside an upper case word
+				for i := start; i < len(b); i++ {
+					b[i] = byte(unicode.ToUpper(rune(b[i])))
+				}
+			} else {
+				b[start] = byte(unicode.ToUpper(rune(b[start])))
+			}
+		}
+		return b
+	}
+	// remove the diacritics
+	start := len(b)
+	for _, d := range norm.NFD.String(string(r)) {
+		switch {
+		case d < utf8.RuneSelf:
+			b = append(b, byte(d))
+		case unicode.Is(unicode.Mn, d):
+			// drop the marks
+		case d != r:
+			// a decomposed base letter, like the greek ones
+			b = t.transliterate(b, d, next)
+		}
+	}
+	if len(b) == start && !unicode.Is(unicode.Mn, r) {
+		b = append(b, '?')
+	}
+	return b
+}
+
+// isApostrophe returns true for the runes used as apostrophe inside words.
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’' || r == 'ʼ'
+}
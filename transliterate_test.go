@@ -0,0 +1,71 @@
+package utf8reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/transform"
+)
+
+func TestTransliterator(t *testing.T) {
+	data := []struct {
+		lang string
+		in   string
+		out  string
+	}{
+		{"bg", "Това е на български", "Tova e na balgarski"},
+		{"bg", "Щастие, ЖАБА, Ж", "Shtastie, ZHABA, Zh"},
+		{"bg", "България, София и Биялица", "Balgaria, Sofia i Biyalitsa"},
+		{"bg", "БЪЛГАРИЯ", "BALGARIA"},
+		{"ru", "Щука и ёжик съели Юлю", "Shchuka i ezhik sieeli Iuliu"},
+		{"uk", "Юрій Їжакевич, Знам'янка, Київ", "Yurii Yizhakevych, Znamianka, Kyiv"},
+		{"uk", "Згорани, Розгон, ЗГУРІВКА", "Zghorany, Rozghon, ZGHURIVKA"},
+		{"de", "Größe über Äpfel", "Groesse ueber Aepfel"},
+		{"de", "GROẞE STRAẞE, ÄRGER", "GROSSE STRASSE, AERGER"},
+		{"de", "Rock'n'Roll", "Rock'n'Roll"},
+		{"", "mouillé Crème brûlée", "mouille Creme brulee"},
+		{"", "Łódź, Ærø, Ελληνικά", "Lodz, Aero, Ellinika"},
+		{"", "«Привет» — 5 €", "\"Privet\" - 5 EUR"},
+		{"", "été", "ete"},
+		{"", "日本", "??"},
+		{"", "invalid \xff", "invalid ?"},
+	}
+	for _, d := range data {
+		tr, err := NewTransliterator(d.lang)
+		if err != nil {
+			t.Errorf("NewTransliterator(%q) error = %v, want nil", d.lang, err)
+			continue
+		}
+		got, _, err := transform.String(tr, d.in)
+		if err != nil || got != d.out {
+			t.Errorf("transliteration %q of %q = %q, %v, want %q, nil", d.lang, d.in, got, err, d.out)
+		}
+		// one byte at a time to check the buffer boundaries
+		b, err := io.ReadAll(transform.NewReader(iotest.OneByteReader(strings.NewReader(d.in)), tr))
+		if err != nil || string(b) != d.out {
+			t.Errorf("one byte reader %q on %q = %q, %v, want %q, nil", d.lang, d.in, b, err, d.out)
+		}
+	}
+
+	if _, err := NewTransliterator("xx"); err == nil {
+		t.Errorf("NewTransliterator(\"xx\") error = nil, want non nil")
+	}
+}
+
+func TestNew_transliteration(t *testing.T) {
+	// koi8-r encoded "Това е на български"
+	in := []byte{0xF4, 0xCF, 0xD7, 0xC1, 0x20, 0xC5, 0x20, 0xCE, 0xC1, 0x20, 0xC2, 0xDF, 0xCC, 0xC7, 0xC1, 0xD2, 0xD3, 0xCB, 0xC9}
+	r := New(strings.NewReader(string(in)), WithTransliteration("bg"))
+	if r == nil {
+		t.Fatalf("New(..., WithTransliteration(\"bg\")) = nil, want *Reader")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "Tova e na balgarski" {
+		t.Errorf("io.ReadAll(r) = %q, %v, want \"Tova e na balgarski\", nil", b, err)
+	}
+	if r := New(strings.NewReader("test"), WithTransliteration("xx")); r != nil {
+		t.Errorf("New(..., WithTransliteration(\"xx\")) = %v, want nil", r)
+	}
+}
@@ -1,22 +1,38 @@
 // Package utf8reader provides a utility to wrap an io.Reader that contains text
 // in an arbitrary encoding and produce an io.Reader that outputs UTF-8 encoded text.
 // The package automatically detects the original encoding and converts the input to UTF-8.
-// Additionally, it can normalize the text to a specified Unicode normalization form (NFC or NFD).
+// Additionally, it can normalize the text to a specified Unicode normalization form
+// (NFC, NFD, NFKC or NFKD) and apply other Unicode-aware transforms like case folding.
 package utf8reader
 
 import (
+	"errors"
 	"io"
+	"log/slog"
+	"time"
 
-	"golang.org/x/net/html/charset"
 	"golang.org/x/text/transform"
 )
 
 // Reader wraps an io.Reader to convert its input to UTF-8 encoding, if required.
 type Reader struct {
 	enc string                // the detected encoding
+	bom int                   // the length of the stripped BOM
 	buf []byte                // the peek buffer used to detect the encoding
 	t   transform.Transformer // the encoding transformer & possibly the normalization transformer
 	tr  io.Reader             // the underlying reader
+
+	limit    int // the bound of the peek window
+	examined int // the number of bytes examined to detect the encoding
+
+	lazy *lazyReader // the lazy detection reader, if any
+
+	sanitizers []*Sanitizer // the sanitizers, if any
+
+	lim       *limiter      // the counter of the input and output bytes, that checks the limits
+	detection time.Duration // the time spent to detect the encoding
+	hooks     []Hook        // the hooks that receive the statistics, if any
+	done      bool          // the hooks were called
 }
 
 // Read reads data from the underlying reader, ensuring it is UTF-8 encoded.
@@ -27,12 +43,19 @@ func (r *Reader) Read(p []byte) (n int, err error) {
 		return 0, io.EOF
 	}
 	r.buf = nil
-	return r.tr.Read(p)
+	n, err = r.tr.Read(p)
+	if err != nil && !r.done {
+		r.done = true
+		for _, h := range r.hooks {
+			h.Done(r.Stats(), err)
+		}
+	}
+	return n, err
 }
 
 // Peek returns a UTF-8 encoded snapshot of the first bytes of the reader,
 // primarily for encoding detection. The size of the snapshot is at most
-// the size of the peek buffer, set by the PeekSize option.
+// the size of the peek buffer, set by the WithPeekSize option.
 // This method must be called before any Read operations.
 func (r *Reader) Peek() ([]byte, error) {
 	if r == nil || r.buf == nil {
@@ -44,6 +67,8 @@ func (r *Reader) Peek() ([]byte, error) {
 	}
 	// transform the buffer
 	tbuf, _, err := transform.Bytes(r.t, r.buf)
+	// reset the (possibly stateful) transformers before reading
+	r.t.Reset()
 	// ignore ErrShortSrc, we transform what we can
 	if err == transform.ErrShortSrc {
 		err = nil
@@ -57,63 +82,178 @@ func (r *Reader) Encoding() string {
 	if r == nil {
 		return ""
 	}
+	if r.lazy != nil && r.lazy.switchAt >= 0 {
+		return r.lazy.enc
+	}
 	return r.enc
 }
 
+// SwitchOffset returns the offset in the input of the first non-ASCII byte,
+// where the encoding was detected in the lazy detection mode (see WithLazyDetection).
+// It returns -1 if no such byte was read so far or if the lazy mode is not used.
+func (r *Reader) SwitchOffset() int64 {
+	if r == nil || r.lazy == nil {
+		return -1
+	}
+	return r.lazy.switchAt
+}
+
+// PeekLimit returns the bound of the peek window: the peek size,
+// or the maximal peek size in the adaptive mode (see WithAdaptivePeek).
+func (r *Reader) PeekLimit() int {
+	if r == nil {
+		return 0
+	}
+	return r.limit
+}
+
+// Examined returns the number of bytes actually examined to detect the encoding.
+func (r *Reader) Examined() int {
+	if r == nil {
+		return 0
+	}
+	return r.examined
+}
+
+// Sanitized returns the number of characters stripped, replaced or escaped
+// by the sanitizers set with the WithSanitize option, so far.
+func (r *Reader) Sanitized() int {
+	if r == nil {
+		return 0
+	}
+	n := 0
+	for _, s := range r.sanitizers {
+		n += s.Count()
+	}
+	return n
+}
+
 // New creates a Reader that converts the input to UTF-8.
 // If encoding detection fails the input stays unchanged,
 // and Encoding() will return an empty string.
-func New(r io.Reader, options ...option) *Reader {
+// If some option is invalid, or if the input can not be read, New returns nil.
+// Use NewReader to get the error.
+func New(r io.Reader, options ...Option) *Reader {
+	reader, _ := NewReader(r, options...)
+	return reader
+}
+
+// NewReader is like New but returns an error if some option is invalid,
+// or if the first bytes of the input can not be read.
+// All the invalid options are reported in the error.
+func NewReader(r io.Reader, options ...Option) (*Reader, error) {
 	if r == nil {
-		return nil
+		return nil, errors.New("utf8reader: nil reader")
 	}
 	params := newParams(options...)
+	if params.err != nil {
+		return nil, params.err
+	}
+	start := time.Now()
+	trace := params.trace
+
+	// count the bytes, for the statistics and the limits
+	lim := &limiter{maxIn: params.maxIn, maxOut: params.maxOut, ratio: params.maxRatio, stats: counts{scan: params.stats}}
+
+	// sample the seekable inputs
+	var samples []byte
+	if params.samples > 0 {
+		var err error
+		if samples, err = sample(r, params.samples, params.peekSize, params.random, lim); err != nil {
+			return nil, err
+		}
+		if samples != nil {
+			trace.emit("sample", "sampled windows of the seekable input", slog.Int("windows", params.samples), slog.Int("bytes", len(samples)))
+		}
+	}
+
+	r = lim.input(r)
 
 	// peek the first bytes to detect the encoding
 	pr, err := newPeekReader(r, params.peekSize)
 	if err != nil {
-		return nil
-	}
-	var encoding string
-	var trs []transform.Transformer
-	if beginning := pr.peek(); len(beginning) > 0 {
-		if bom, lb := detectBOM(beginning); bom != "" {
-			encoding = bom
-			pr.skip(lb)
-		} else {
-			encoding = detectCharset(beginning)
-		}
-		if encoding != "UTF-8" && encoding != "" {
-			if e, _ := charset.Lookup(encoding); e != nil {
-				trs = append(trs, e.NewDecoder())
+		return nil, err
+	}
+	trace.emit("peek", "peeked the first bytes", slog.Int("bytes", len(pr.peek())), slog.Int("size", params.peekSize))
+	limit := max(params.peekSize, params.maxPeekSize)
+	eof := len(pr.peek()) < params.peekSize // the whole input is peeked
+	if samples != nil {
+		limit = params.samples * params.peekSize
+	} else if params.maxPeekSize > 0 && len(pr.peek()) == params.peekSize {
+		// adaptive mode: extend the peek window while the detection is not decisive
+		for len(pr.peek()) < limit {
+			// only the final detection is traced
+			_, confidence := detectCharsetConfidence(pr.peek())
+			if confidence > 0 && confidence >= params.confidence {
+				break
+			}
+			want := min(len(pr.peek()), limit-len(pr.peek()))
+			n, err := pr.grow(want)
+			if err != nil {
+				return nil, err
+			}
+			trace.emit("adaptive", "the detection is not decisive, the peek window grows",
+				slog.Int("confidence", confidence), slog.Int("bytes", len(pr.peek())))
+			if n < want {
+				eof = true
+				break
 			}
 		}
 	}
+	if samples == nil {
+		samples = pr.peek()
+	} else {
+		// the sampled windows are cut at the rune boundaries
+		eof = false
+	}
+	examined := len(samples)
 
-	// add the (normalization) transformer(s)
-	trs = append(trs, params.transformers...)
+	det := detect(pr.peek(), samples, eof, params)
+	encoding, lbom := det.Encoding, det.BOM
+	pr.skip(lbom)
+	var lazy *lazyReader
+	if params.lazy && lbom == 0 && encoding == "UTF-8" && det.Confidence == 0 {
+		// ascii so far, the detection is deferred
+		trace.emit("lazy", "ASCII so far, the detection is deferred to the first non-ASCII byte")
+		lazy = newLazyReader(pr, params, lim)
+	}
 
 	// set the buffer
 	reader := &Reader{
-		enc: encoding,
-		buf: pr.peek(),
+		enc:        encoding,
+		bom:        lbom,
+		buf:        pr.peek(),
+		limit:      limit,
+		examined:   examined,
+		sanitizers: params.sanitizers,
+		lazy:       lazy,
+		lim:        lim,
+		detection:  time.Since(start),
+		hooks:      params.hooks,
 	}
-	// chain the transformers
-	var tr transform.Transformer
-	if encoding != "" {
-		if len(trs) > 1 {
-			tr = transform.Chain(trs...)
-		} else if len(trs) == 1 {
-			tr = trs[0]
-		}
+	trace.emit("result", "encoding used by the reader", slog.String("encoding", encoding), slog.Duration("time", reader.detection))
+	for _, h := range reader.hooks {
+		h.Detected(encoding, reader.detection)
+	}
+	var src io.Reader = pr
+	if lazy != nil {
+		src = lazy
 	}
+	// chain the transformers
+	tr := params.transformer(encoding)
 	// install the transformer
 	if tr == nil {
-		reader.tr = pr
-		return reader
+		reader.tr = src
+	} else {
+		reader.t = tr
+		if lazy == nil {
+			// the lazy reader counts the consumed bytes itself
+			tr = lim.consume(tr)
+		}
+		reader.tr = transform.NewReader(src, tr)
 	}
-	reader.t = tr
-	reader.tr = transform.NewReader(pr, tr)
+	lim.consumed = int64(lbom)
+	reader.tr = lim.output(reader.tr, tr == nil && lazy == nil)
 	// ready to read
-	return reader
+	return reader, nil
 }
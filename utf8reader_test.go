@@ -77,6 +77,21 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewReader(t *testing.T) {
+	if r, err := NewReader(nil); r != nil || err == nil {
+		t.Errorf("NewReader(nil) = %v, %v, want nil, error", r, err)
+	}
+	for _, size := range []int{0, -1, 100, MaxPeekSize + 1} {
+		if r, err := NewReader(strings.NewReader("test"), WithPeekSize(size)); r != nil || err == nil {
+			t.Errorf("NewReader(..., WithPeekSize(%d)) = %v, %v, want nil, error", size, r, err)
+		}
+	}
+	r, err := NewReader(strings.NewReader("test"), WithPeekSize(MinPeekSize))
+	if r == nil || err != nil {
+		t.Errorf("NewReader(..., WithPeekSize(MinPeekSize)) = %v, %v, want *Reader, nil", r, err)
+	}
+}
+
 func TestPeek(t *testing.T) {
 	r := New(strings.NewReader("test"))
 	if r == nil {
@@ -154,6 +169,12 @@ func TestRead(t *testing.T) {
 			in:       []byte{0xfe, 0xff, 0x00, 0x62, 0x00, 0xe9, 0x00, 0x74, 0x00, 0xe0},
 			out:      []byte{0x62, 0xc3, 0xa9, 0x74, 0xc3, 0xa0},
 		},
+		{
+			name:     "bétà : UTF-32LE with BOM",
+			encoding: "UTF-32LE",
+			in:       []byte{0xff, 0xfe, 0x00, 0x00, 0x62, 0x00, 0x00, 0x00, 0xe9, 0x00, 0x00, 0x00, 0x74, 0x00, 0x00, 0x00, 0xe0, 0x00, 0x00, 0x00},
+			out:      []byte{0x62, 0xc3, 0xa9, 0x74, 0xc3, 0xa0},
+		},
 		{
 			name:     "C'est bête en français : iso-8859-1",
 			encoding: "ISO-8859-1",
@@ -194,3 +215,76 @@ func TestRead(t *testing.T) {
 		}
 	}
 }
+
+func TestNew_adaptivePeek(t *testing.T) {
+	// 8000 bytes of ASCII followed by an iso-8859-1 "C'est bête en français"
+	in := append(bytes.Repeat([]byte("ascii header\n"), 8000/13), 0x43, 0x27, 0x65, 0x73, 0x74, 0x20, 0x62, 0xEA, 0x74, 0x65, 0x20, 0x65, 0x6E, 0x20, 0x66, 0x72, 0x61, 0x6E, 0xE7, 0x61, 0x69, 0x73)
+
+	r := New(bytes.NewReader(in))
+	if r.Encoding() != "UTF-8" || r.Examined() != 4096 || r.PeekLimit() != 4096 {
+		t.Errorf("New(...) = %s, %d, %d, want UTF-8, 4096, 4096", r.Encoding(), r.Examined(), r.PeekLimit())
+	}
+
+	r, err := NewReader(bytes.NewReader(in), WithAdaptivePeek(1<<16))
+	if err != nil {
+		t.Fatalf("NewReader(..., WithAdaptivePeek(1<<16)) error = %v, want nil", err)
+	}
+	if r.Encoding() == "UTF-8" || r.Examined() != len(in) || r.PeekLimit() != 1<<16 {
+		t.Errorf("NewReader(..., WithAdaptivePeek(1<<16)) = %s, %d, %d, want not UTF-8, %d, %d", r.Encoding(), r.Examined(), r.PeekLimit(), len(in), 1<<16)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil || !bytes.HasSuffix(b, []byte("C'est bête en français")) || len(b) != len(in)+2 {
+		t.Errorf("io.ReadAll(r) = ...%q, %v, want ...\"C'est bête en français\", nil", b[len(b)-30:], err)
+	}
+
+	// the first non-ASCII rune stops the adaptive peek
+	in = append(bytes.Repeat([]byte("a"), 5000), "é"...)
+	in = append(in, bytes.Repeat([]byte("b"), 100000)...)
+	r, err = NewReader(bytes.NewReader(in), WithAdaptivePeek(1<<20))
+	if err != nil || r.Encoding() != "UTF-8" || r.Examined() != 8192 {
+		t.Errorf("NewReader(..., WithAdaptivePeek(1<<20)) = %s, %d, %v, want UTF-8, 8192, nil", r.Encoding(), r.Examined(), err)
+	}
+	b, err = io.ReadAll(r)
+	if err != nil || !bytes.Equal(b, in) {
+		t.Errorf("io.ReadAll(r) differs from the input, err = %v", err)
+	}
+
+	if _, err := NewReader(bytes.NewReader(in), WithAdaptivePeek(10)); err == nil {
+		t.Errorf("NewReader(..., WithAdaptivePeek(10)) error = nil, want non nil")
+	}
+	if _, err := NewReader(bytes.NewReader(in), WithPeekSize(8192), WithAdaptivePeek(4096)); err == nil {
+		t.Errorf("NewReader(..., WithPeekSize(8192), WithAdaptivePeek(4096)) error = nil, want non nil")
+	}
+	if _, err := NewReader(bytes.NewReader(in), WithMinConfidence(101)); err == nil {
+		t.Errorf("NewReader(..., WithMinConfidence(101)) error = nil, want non nil")
+	}
+}
+
+func TestNew_hint(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		hint string
+		enc  string
+		out  string
+	}{
+		{"declared", "caf\xe9", "iso-8859-1", "iso-8859-1", "café"},
+		{"declared ascii", "cafe", "windows-1251", "windows-1251", "cafe"},
+		{"declared short", "\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff", "windows-1251", "windows-1251", "България"},
+		{"mislabeled utf-8", "café", "iso-8859-1", "UTF-8", "café"},
+		{"declared utf-8", "café", "utf8", "UTF-8", "café"},
+		{"invalid utf-8", "caf\xe9 cr\xe8me br\xfbl\xe9e", "utf-8", "ISO-8859-1", "café crème brûlée"},
+		{"unknown", "café", "unknown", "UTF-8", "café"},
+		{"bom", "\xfe\xff\x00c\x00a\x00f\x00\xe9", "iso-8859-1", "UTF-16BE", "café"},
+	}
+	for _, tt := range tests {
+		r, err := NewReader(strings.NewReader(tt.in), WithHint(tt.hint))
+		if err != nil {
+			t.Fatalf("%s: NewReader(..., WithHint(%q)) error = %v, want nil", tt.name, tt.hint, err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil || string(b) != tt.out || r.Encoding() != tt.enc {
+			t.Errorf("%s: WithHint(%q): %q, %v, %s, want %q, nil, %s", tt.name, tt.hint, b, err, r.Encoding(), tt.out, tt.enc)
+		}
+	}
+}
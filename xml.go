@@ -0,0 +1,26 @@
+package utf8reader
+
+import (
+	"fmt"
+	"io"
+)
+
+// XMLCharsetReader converts the input of an xml.Decoder, in the charset declared
+// by the XML declaration, to UTF-8. It is suited to the CharsetReader field of xml.Decoder:
+//
+//	d := xml.NewDecoder(r)
+//	d.CharsetReader = utf8reader.XMLCharsetReader
+//
+// The declared charset is used as a hint (see WithHint): the input that is
+// valid UTF-8 with some non-ASCII characters, a common mislabeling, is kept in UTF-8.
+// It returns an error if the charset is unknown.
+func XMLCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	if lookup(charset) == nil {
+		return nil, fmt.Errorf("utf8reader: unknown XML charset %q", charset)
+	}
+	r, err := NewReader(input, WithHint(charset))
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
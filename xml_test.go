@@ -0,0 +1,34 @@
+package utf8reader
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestXMLCharsetReader(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"<?xml version=\"1.0\" encoding=\"windows-1251\"?><name>\xc1\xfa\xeb\xe3\xe0\xf0\xe8\xff</name>", "България"},
+		{"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><name>Caf\xe9</name>", "Café"},
+		// mislabeled UTF-8
+		{"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><name>Café</name>", "Café"},
+		{"<?xml version=\"1.0\" encoding=\"UTF-8\"?><name>Café</name>", "Café"},
+	}
+	for _, tt := range tests {
+		d := xml.NewDecoder(strings.NewReader(tt.in))
+		d.CharsetReader = XMLCharsetReader
+		var v struct {
+			Name string `xml:",chardata"`
+		}
+		if err := d.Decode(&v); err != nil || v.Name != tt.want {
+			t.Errorf("Decode(%q) = %q, %v, want %q, nil", tt.in, v.Name, err, tt.want)
+		}
+	}
+
+	if _, err := XMLCharsetReader("latin-42", strings.NewReader("")); err == nil {
+		t.Errorf("XMLCharsetReader(\"latin-42\", ...) error = nil, want non nil")
+	}
+}